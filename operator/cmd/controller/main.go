@@ -1,18 +1,39 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/authz"
+	"github.com/awslabs/kit/operator/pkg/bugreport"
 	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/clusterset"
 	"github.com/awslabs/kit/operator/pkg/controllers/controlplane"
+	"github.com/awslabs/kit/operator/pkg/controllers/loadtest"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/graph"
+	"github.com/awslabs/kit/operator/pkg/guard"
+	"github.com/awslabs/kit/operator/pkg/health"
+	"github.com/awslabs/kit/operator/pkg/logs"
+	"github.com/awslabs/kit/operator/pkg/notifications"
+	"github.com/awslabs/kit/operator/pkg/quota"
+	"github.com/awslabs/kit/operator/pkg/references"
+	"github.com/awslabs/kit/operator/pkg/sharding"
 
 	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"knative.dev/pkg/logging"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
@@ -23,6 +44,10 @@ var (
 	options = Options{}
 )
 
+// vpcCIDRsTimeout bounds waiting on the instance metadata, which doesn't
+// answer off EC2
+const vpcCIDRsTimeout = 5 * time.Second
+
 func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 	_ = v1alpha1.AddToScheme(scheme)
@@ -31,24 +56,76 @@ func init() {
 // Options for running this binary
 type Options struct {
 	EnableVerboseLogging bool
+	LogFormat            string
 	MetricsPort          int
 	WebhookPort          int
+	// FederationRemoteWriteURL collects metrics from all guest clusters
+	FederationRemoteWriteURL string
+	// EventBusName and EventTopicARN receive the cluster lifecycle events
+	EventBusName  string
+	EventTopicARN string
+	// WebhookURL and WebhookRoutes receive notifications on failures
+	WebhookURL           string
+	WebhookRoutes        string
+	StuckDeletionTimeout time.Duration
+	StallTimeout         time.Duration
+	// SyncPeriod and SyncPeriods set how often converged resources are
+	// checked for drift
+	SyncPeriod  time.Duration
+	SyncPeriods string
+	// Sharding spreads the resources over all the replicas instead of
+	// electing a leader
+	Sharding bool
+	// QuotaPreflight checks the EC2 quotas of the account before a cluster is
+	// provisioned
+	QuotaPreflight bool
+	// QuotaIncreaseRequests requests an increase of a quota the preflight
+	// check found insufficient
+	QuotaIncreaseRequests bool
+	// ValidateReferences checks the AWS resources a cluster refers to exist
+	// before it is provisioned
+	ValidateReferences bool
+	// DetectTagTampering checks the tags of the AWS resources of the clusters
+	// weren't changed outside the operator
+	DetectTagTampering bool
 }
 
 func main() {
 	flag.BoolVar(&options.EnableVerboseLogging, "verbose", false, "Enable verbose logging")
+	flag.StringVar(&options.LogFormat, "log-format", "console", "Log encoding, console or json")
 	flag.IntVar(&options.WebhookPort, "webhook-port", 9443, "The port the webhook endpoint binds to for validation and mutation of resources")
 	flag.IntVar(&options.MetricsPort, "metrics-port", 8080, "The port the metric endpoint binds to for operating metrics about the controller itself")
+	flag.StringVar(&options.FederationRemoteWriteURL, "federation-remote-write-url", "", "Amazon Managed Prometheus remote write endpoint the key SLI metrics of every guest cluster are sent to")
+	flag.StringVar(&options.EventBusName, "event-bus-name", "", "EventBridge bus cluster lifecycle events are put on")
+	flag.StringVar(&options.EventTopicARN, "event-topic-arn", "", "SNS topic cluster lifecycle events are published to")
+	flag.StringVar(&options.WebhookURL, "notification-webhook-url", "", "Slack compatible webhook notified of failing clusters and stuck deletions")
+	flag.StringVar(&options.WebhookRoutes, "notification-webhook-routes", "", "Comma separated namespace=url pairs routing notifications of clusters in a namespace to its own webhook")
+	flag.DurationVar(&options.StuckDeletionTimeout, "stuck-deletion-timeout", 10*time.Minute, "How long a cluster can be deleting before its deletion is reported as stuck")
+	flag.DurationVar(&options.StallTimeout, "stall-timeout", 30*time.Minute, "How long a resource can wait on a dependency before it is marked as Stalled")
+	flag.DurationVar(&options.SyncPeriod, "sync-period", time.Minute, "How often a resource is reconciled once it has converged, to detect drift")
+	flag.StringVar(&options.SyncPeriods, "sync-periods", "", "Comma separated controller=duration pairs overriding --sync-period per controller, e.g. control-plane=5m,load-test=30s")
+	flag.BoolVar(&options.Sharding, "sharding", false, "Spread the resources over all the replicas, instead of reconciling them all on the elected leader")
+	flag.BoolVar(&options.QuotaPreflight, "quota-preflight", false, "Check the on-demand vCPU quota of the account before provisioning a cluster")
+	flag.BoolVar(&options.QuotaIncreaseRequests, "quota-increase-requests", false, "Request an increase of the quota when the preflight check fails, and wait for it instead of failing")
+	flag.BoolVar(&options.ValidateReferences, "validate-references", false, "Check the instance types and artifact bucket of a cluster exist, and its CIDRs don't overlap the VPC, before provisioning it")
+	flag.BoolVar(&options.DetectTagTampering, "detect-tag-tampering", false, "Record an event on a cluster when the tags of its apiserver load balancer were changed outside the operator")
 	flag.Parse()
+	controllers.StallTimeout = options.StallTimeout
+	controllers.SyncPeriod = options.SyncPeriod
+	syncPeriods, err := controllers.ParseSyncPeriods(options.SyncPeriods)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to parse sync periods, %v", err))
+	}
+	controllers.SyncPeriods = syncPeriods
 
 	logger := controllerruntimezap.NewRaw(controllerruntimezap.UseDevMode(options.EnableVerboseLogging),
-		controllerruntimezap.ConsoleEncoder(),
+		encoderFor(options.LogFormat),
 		controllerruntimezap.StacktraceLevel(zapcore.DPanicLevel))
 	controllerruntime.SetLogger(zapr.NewLogger(logger))
 	zap.ReplaceGlobals(logger)
 
 	manager := controllers.NewManagerOrDie(controllerruntime.GetConfigOrDie(), controllerruntime.Options{
-		LeaderElection:          true,
+		LeaderElection:          !options.Sharding,
 		LeaderElectionID:        "kit-leader-election",
 		Scheme:                  scheme,
 		MetricsBindAddress:      fmt.Sprintf(":%d", options.MetricsPort),
@@ -56,9 +133,124 @@ func main() {
 		LeaderElectionNamespace: "kit",
 	})
 
-	err := manager.RegisterControllers(
-		controlplane.NewController(manager.GetClient())).Start(controllerruntime.SetupSignalHandler())
+	clientSet := kubernetes.NewForConfigOrDie(manager.GetConfig())
+	if err := manager.AddMetricsExtraHandler(graph.Path, authz.NewHandler(clientSet, graph.Path, "graph", graph.NewHandler(manager.GetClient()))); err != nil {
+		panic(fmt.Sprintf("Unable to serve the dependency graph, %v", err))
+	}
+	if err := manager.AddMetricsExtraHandler(logs.Path, authz.NewHandler(clientSet, logs.Path, "logs", logs.NewHandler(clientSet))); err != nil {
+		panic(fmt.Sprintf("Unable to serve the component logs, %v", err))
+	}
+	// The hostname of a pod is its name
+	identity, err := os.Hostname()
+	if err != nil {
+		panic(fmt.Sprintf("Unable to get the pod name, %v", err))
+	}
+	if err := manager.AddMetricsExtraHandler(bugreport.Path, authz.NewHandler(clientSet, bugreport.Path, "bugreport",
+		bugreport.NewHandler(manager.GetClient(), clientSet, types.NamespacedName{Namespace: "kit", Name: identity}))); err != nil {
+		panic(fmt.Sprintf("Unable to serve the bug reports, %v", err))
+	}
+	master.VPCCIDRs = vpcCIDRsFor(logger.Sugar())
+	if options.Sharding {
+		membership := sharding.New(manager.GetClient(), manager.GetAPIReader(), "kit", identity)
+		if err := manager.Add(membership); err != nil {
+			panic(fmt.Sprintf("Unable to join the shards, %v", err))
+		}
+		controllers.Shard = membership
+	}
+	err = manager.RegisterControllers(
+		controlplane.NewController(manager.GetClient(), controlplane.Options{
+			FederationRemoteWriteURL: options.FederationRemoteWriteURL,
+			Publisher:                publisherFor(options),
+			StuckDeletionTimeout:     options.StuckDeletionTimeout,
+			Quotas:                   quotasFor(options),
+			References:               referencesFor(options),
+			Recorder:                 manager.GetEventRecorderFor("control-plane"),
+			Prober:                   health.NewGuest(manager.GetClient()),
+			Guard:                    guardFor(options),
+		}),
+		clusterset.NewController(manager.GetClient()),
+		loadtest.NewController(manager.GetClient(), clientSet),
+	).Start(logging.WithLogger(controllerruntime.SetupSignalHandler(), logger.Sugar()))
 	if err != nil {
 		panic(fmt.Sprintf("Unable to start manager, %v", err))
 	}
 }
+
+func encoderFor(format string) controllerruntimezap.Opts {
+	switch format {
+	case "json":
+		return controllerruntimezap.JSONEncoder()
+	case "console":
+		return controllerruntimezap.ConsoleEncoder()
+	default:
+		panic(fmt.Sprintf("Unsupported log format %q, expected console or json", format))
+	}
+}
+
+func quotasFor(options Options) quota.Checker {
+	if !options.QuotaPreflight && !options.QuotaIncreaseRequests {
+		return nil
+	}
+	return quota.NewServiceQuotas(session.Must(session.NewSession()), options.QuotaIncreaseRequests)
+}
+
+// vpcCIDRsFor looks up the VPC CIDRs once, when the first cluster with a proxy
+// is reconciled. The components of the clusters reach the nodes in the VPC
+// without their proxy, the VPC is only known on EC2.
+func vpcCIDRsFor(logger *zap.SugaredLogger) func() []string {
+	var once sync.Once
+	var cidrs []string
+	return func() []string {
+		once.Do(func() {
+			session, err := session.NewSession()
+			if err != nil {
+				logger.Infof("Unable to find the VPC CIDRs, they aren't excluded from the proxy of the clusters, %v", err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), vpcCIDRsTimeout)
+			defer cancel()
+			if cidrs, err = references.VPCCIDRs(ctx, session); err != nil {
+				logger.Infof("Unable to find the VPC CIDRs, they aren't excluded from the proxy of the clusters, %v", err)
+			}
+		})
+		return cidrs
+	}
+}
+
+func referencesFor(options Options) references.Validator {
+	if !options.ValidateReferences {
+		return nil
+	}
+	return references.NewAWS(session.Must(session.NewSession()))
+}
+
+func guardFor(options Options) guard.Detector {
+	if !options.DetectTagTampering {
+		return nil
+	}
+	return guard.NewAWS(session.Must(session.NewSession()))
+}
+
+func publisherFor(options Options) notifications.Publisher {
+	publishers := notifications.Publishers{}
+	if options.EventBusName != "" || options.EventTopicARN != "" {
+		session := session.Must(session.NewSession())
+		if options.EventBusName != "" {
+			publishers = append(publishers, notifications.NewEventBridge(session, options.EventBusName))
+		}
+		if options.EventTopicARN != "" {
+			publishers = append(publishers, notifications.NewSNS(session, options.EventTopicARN))
+		}
+	}
+	if options.WebhookURL != "" || options.WebhookRoutes != "" {
+		routes, err := notifications.ParseRoutes(options.WebhookRoutes)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to parse webhook routes, %v", err))
+		}
+		publishers = append(publishers, notifications.NewWebhook(options.WebhookURL, routes))
+	}
+	if len(publishers) == 0 {
+		return nil
+	}
+	return publishers
+}
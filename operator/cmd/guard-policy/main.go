@@ -0,0 +1,44 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/guard"
+)
+
+// guard-policy prints a policy denying changes to the AWS resources KIT
+// provisions to everyone but the given principals, e.g.
+// go run ./cmd/guard-policy --allowed-principals arn:aws:iam::123456789012:role/AWSLoadBalancerControllerRole
+func main() {
+	allowedPrincipals := flag.String("allowed-principals", "", "Comma separated ARNs of the principals allowed to change the resources, e.g. the role of the load balancer controller")
+	flag.Parse()
+	var principals []string
+	for _, principal := range strings.Split(*allowedPrincipals, ",") {
+		if principal = strings.TrimSpace(principal); principal != "" {
+			principals = append(principals, principal)
+		}
+	}
+	policy, err := guard.Policy(principals)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "generating policy, %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(policy))
+}
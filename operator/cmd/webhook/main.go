@@ -17,17 +17,27 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1beta1"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection"
+	"knative.dev/pkg/injection/clients/dynamicclient"
 	"knative.dev/pkg/injection/sharedmain"
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
 	"knative.dev/pkg/webhook/certificates"
+	"knative.dev/pkg/webhook/resourcesemantics/conversion"
 	"knative.dev/pkg/webhook/resourcesemantics/defaulting"
 	"knative.dev/pkg/webhook/resourcesemantics/validation"
 )
@@ -38,10 +48,14 @@ var (
 
 type Options struct {
 	Port int
+	// RequireDeletionConfirmation rejects deleting ControlPlanes which aren't
+	// annotated with the confirmation annotation
+	RequireDeletionConfirmation bool
 }
 
 func main() {
 	flag.IntVar(&options.Port, "port", 8443, "The port the webhook endpoint binds to for validation and mutation of resources")
+	flag.BoolVar(&options.RequireDeletionConfirmation, "require-deletion-confirmation", false, "Reject deleting control planes without the "+v1alpha1.DeletionConfirmationAnnotationKey+" annotation set to their name")
 	flag.Parse()
 
 	config := injection.ParseAndGetRESTConfigOrDie()
@@ -58,6 +72,7 @@ func main() {
 		certificates.NewController,
 		NewCRDDefaultingWebhook,
 		NewCRDValidationWebhook,
+		NewCRDConversionWebhook,
 	)
 }
 
@@ -78,7 +93,54 @@ func NewCRDValidationWebhook(ctx context.Context, w configmap.Watcher) *controll
 		v1alpha1.Resources,
 		InjectContext,
 		true,
+		map[schema.GroupVersionKind]validation.Callback{
+			v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.ControlPlaneKind): validation.NewCallback(
+				ValidateControlPlaneDelete(dynamicclient.Get(ctx).Resource(v1alpha1.SchemeGroupVersion.WithResource("clustertemplates"))), webhook.Delete),
+		},
 	)
 }
 
+func NewCRDConversionWebhook(ctx context.Context, w configmap.Watcher) *controller.Impl {
+	return conversion.NewConversionController(ctx,
+		"/resource-conversion",
+		map[schema.GroupKind]conversion.GroupKindConversion{
+			v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.ControlPlaneKind).GroupKind(): {
+				DefinitionName: "controlplanes.kit.k8s.sh",
+				HubVersion:     v1alpha1.APIVersion,
+				Zygotes: map[string]conversion.ConvertibleObject{
+					v1alpha1.APIVersion: &v1alpha1.ControlPlane{},
+					v1beta1.APIVersion:  &v1beta1.ControlPlane{},
+				},
+			},
+		},
+		InjectContext,
+	)
+}
+
+// ValidateControlPlaneDelete isn't part of Validate as the validation webhook
+// only calls it on create and update. The ClusterTemplate of the ControlPlane
+// is read as its deletion protection applies too.
+func ValidateControlPlaneDelete(templates dynamic.NamespaceableResourceInterface) func(context.Context, *unstructured.Unstructured) error {
+	return func(ctx context.Context, unstructured *unstructured.Unstructured) error {
+		controlPlane := &v1alpha1.ControlPlane{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructured.Object, controlPlane); err != nil {
+			return fmt.Errorf("converting control plane, %w", err)
+		}
+		var template *v1alpha1.ClusterTemplate
+		if controlPlane.Spec.Template != "" {
+			object, err := templates.Namespace(controlPlane.Namespace).Get(ctx, controlPlane.Spec.Template, metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("getting cluster template %s, %w", controlPlane.Spec.Template, err)
+			}
+			if err == nil {
+				template = &v1alpha1.ClusterTemplate{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, template); err != nil {
+					return fmt.Errorf("converting cluster template, %w", err)
+				}
+			}
+		}
+		return controlPlane.ValidateDelete(template, options.RequireDeletionConfirmation)
+	}
+}
+
 func InjectContext(ctx context.Context) context.Context { return ctx }
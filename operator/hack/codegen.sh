@@ -11,12 +11,26 @@ controller-gen \
 ./hack/boilerplate.sh
 
 mv config/kit.k8s.sh_controlplanes.yaml config/control-plane-crd.yaml
-# CRDs don't currently jive with VolatileTime, which has an Any type.
-perl -pi -e 's/Any/string/g' config/control-plane-crd.yaml
+mv config/kit.k8s.sh_clustertemplates.yaml config/cluster-template-crd.yaml
+mv config/kit.k8s.sh_clustersets.yaml config/cluster-set-crd.yaml
+mv config/kit.k8s.sh_loadtests.yaml config/load-test-crd.yaml
 
-# Kubectl apply fails if the annotations is too long with error -
-# The CustomResourceDefinition "controlplanes.kit.k8s.sh" is invalid: metadata.annotations: Too long: must have at most 262144 bytes
-# More info: https://stackoverflow.com/a/62409266
-yq eval 'del(.. | select(has("description")).description)' -i config/control-plane-crd.yaml
-yq eval 'del(.. | select(has("ephemeralContainers")).ephemeralContainers)' -i config/control-plane-crd.yaml
-yq eval 'del(.. | select(has("initContainers")).initContainers)' -i config/control-plane-crd.yaml
\ No newline at end of file
+for crd in config/control-plane-crd.yaml config/cluster-template-crd.yaml config/cluster-set-crd.yaml config/load-test-crd.yaml; do
+    # CRDs don't currently jive with VolatileTime, which has an Any type.
+    perl -pi -e 's/Any/string/g' $crd
+
+    # Kubectl apply fails if the annotations is too long with error -
+    # The CustomResourceDefinition "controlplanes.kit.k8s.sh" is invalid: metadata.annotations: Too long: must have at most 262144 bytes
+    # More info: https://stackoverflow.com/a/62409266
+    yq eval 'del(.. | select(has("description")).description)' -i $crd
+    yq eval 'del(.. | select(has("ephemeralContainers")).ephemeralContainers)' -i $crd
+    yq eval 'del(.. | select(has("initContainers")).initContainers)' -i $crd
+done
+
+# Cluster API discovers which of its contract versions a provider CRD
+# implements from this label.
+yq eval '.metadata.labels."cluster.x-k8s.io/v1alpha4" = "v1alpha1"' -i config/control-plane-crd.yaml
+
+# ControlPlanes are converted between versions by the webhook, it fills in the
+# CA bundle and path of the conversion config.
+yq eval '.spec.conversion = {"strategy": "Webhook", "webhook": {"conversionReviewVersions": ["v1", "v1beta1"], "clientConfig": {"service": {"name": "kit-webhook", "namespace": "kit"}}}}' -i config/control-plane-crd.yaml
@@ -14,6 +14,12 @@ limitations under the License.
 
 package config
 
+// The images the components run when a ControlPlane doesn't override them,
+// DefaultKubernetesVersion is the version of the master images.
 const (
-	DefaultKubernetesVersion = "1.19"
+	DefaultKubernetesVersion      = "1.20"
+	DefaultAPIServerImage         = "public.ecr.aws/eks-distro/kubernetes/kube-apiserver:v1.20.7-eks-1-20-4"
+	DefaultControllerManagerImage = "public.ecr.aws/eks-distro/kubernetes/kube-controller-manager:v1.20.7-eks-1-20-4"
+	DefaultSchedulerImage         = "public.ecr.aws/eks-distro/kubernetes/kube-scheduler:v1.20.7-eks-1-20-4"
+	DefaultEtcdImage              = "public.ecr.aws/eks-distro/etcd-io/etcd:v3.4.14-eks-1-18-1"
 )
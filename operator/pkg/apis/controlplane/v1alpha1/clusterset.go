@@ -0,0 +1,115 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
+)
+
+// ClusterSet is the Schema for the ClusterSets API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".spec.replicas"
+// +kubebuilder:printcolumn:name="Current",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type ClusterSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSetSpec   `json:"spec,omitempty"`
+	Status ClusterSetStatus `json:"status,omitempty"`
+}
+
+// ClusterSetList contains a list of ClusterSet
+// +kubebuilder:object:root=true
+type ClusterSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSet `json:"items"`
+}
+
+// ClusterSetSpec manages a number of identical ControlPlanes, similar to how a
+// ReplicaSet manages pods. Member ControlPlanes are named <clusterset>-<index>
+// and are created or deleted to match the desired replicas.
+type ClusterSetSpec struct {
+	// Replicas is the desired number of ControlPlanes in this set.
+	// +kubebuilder:validation:Minimum=0
+	Replicas int32 `json:"replicas,omitempty"`
+	// Template is the spec given to every ControlPlane in this set, it can
+	// reference a ClusterTemplate with template.template.
+	Template ControlPlaneSpec `json:"template,omitempty"`
+}
+
+// ClusterSetStatus defines the observed state of the ClusterSet
+type ClusterSetStatus struct {
+	// Conditions is the set of conditions required for this ClusterSet to
+	// manage its ControlPlanes, and indicates whether or not those conditions
+	// are met.
+	// +optional
+	Conditions apis.Conditions `json:"conditions,omitempty"`
+	// Replicas is the number of ControlPlanes that currently exist in this set.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// ReadyReplicas is the number of ControlPlanes in this set which are ready.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+}
+
+// MemberNameFor returns the name of the ControlPlane at index in the set
+func (c *ClusterSet) MemberNameFor(index int) string {
+	return fmt.Sprintf("%s-%d", c.Name, index)
+}
+
+// SetDefaults for the ClusterSet, the template is defaulted for every member
+// when it's created
+func (c *ClusterSet) SetDefaults(_ context.Context) {}
+
+func (c *ClusterSet) Validate(ctx context.Context) (errs *apis.FieldError) {
+	if apis.IsInStatusUpdate(ctx) {
+		return nil
+	}
+	if msgs := validation.IsDNS1035Label(c.Name); len(msgs) > 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, %s", c.Name, strings.Join(msgs, ", ")), "metadata.name"))
+	}
+	// The member with the highest index has the longest name, it's checked
+	// on updates too as adding replicas makes it longer
+	if c.Spec.Replicas > 0 {
+		if name := c.MemberNameFor(int(c.Spec.Replicas) - 1); len(name) > MaxNameLength {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("member %s would be longer than %d characters, shorten the name or reduce the replicas", name, MaxNameLength), "metadata.name", "spec.replicas"))
+		}
+	}
+	return errs
+}
+
+func (c *ClusterSet) StatusConditions() apis.ConditionManager {
+	return apis.NewLivingConditionSet(
+		Active,
+	).Manage(c)
+}
+
+func (c *ClusterSet) GetConditions() apis.Conditions {
+	return c.Status.Conditions
+}
+
+func (c *ClusterSet) SetConditions(conditions apis.Conditions) {
+	c.Status.Conditions = conditions
+}
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imdario/mergo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterTemplate is the Schema for the ClusterTemplates API
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=ct
+type ClusterTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterTemplateSpec `json:"spec,omitempty"`
+}
+
+// ClusterTemplateList contains a list of ClusterTemplate
+// +kubebuilder:object:root=true
+type ClusterTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterTemplate `json:"items"`
+}
+
+// ClusterTemplateSpec is a reusable blueprint for clusters. ControlPlanes
+// referencing a template by name in spec.template inherit every field they
+// don't set themselves, so the fields set on the ControlPlane are the
+// overrides for that particular cluster.
+type ClusterTemplateSpec struct {
+	ControlPlane ControlPlaneSpec `json:"controlPlane,omitempty"`
+}
+
+// ApplyTemplate sets the fields the spec doesn't set from the template and
+// defaults the result, like the operator does before rendering a cluster.
+func (s *ControlPlaneSpec) ApplyTemplate(ctx context.Context, template *ClusterTemplate) error {
+	if err := mergo.Merge(s, template.Spec.ControlPlane); err != nil {
+		return fmt.Errorf("applying cluster template %s, %w", template.Name, err)
+	}
+	s.SetDefaults(ctx)
+	return nil
+}
@@ -15,13 +15,26 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // ControlPlane is the Schema for the ControlPlanes API
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=cp
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Active\")].status"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Endpoint",type="string",JSONPath=".status.endpoint"
+// +kubebuilder:printcolumn:name="Uptime",type="string",JSONPath=".status.availability.uptime",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:storageversion
 type ControlPlane struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -42,9 +55,209 @@ type ControlPlaneList struct {
 // master and etcd are configured to run. By default, KIT uses all the default
 // values and ControlPlaneSpec can be empty.
 type ControlPlaneSpec struct {
+	// Template is the name of a ClusterTemplate in the same namespace, fields
+	// not set in this spec are inherited from the template.
+	// +optional
+	Template string `json:"template,omitempty"`
+	// KubernetesVersion is the minor version of the control plane, e.g. 1.20.
+	// +kubebuilder:validation:Pattern=`^1\.[0-9]+$`
+	// +optional
 	KubernetesVersion string     `json:"kubernetesVersion,omitempty"`
 	Master            MasterSpec `json:"master,omitempty"`
 	Etcd              ETCDSpec   `json:"etcd,omitempty"`
+	// Paused scales the master components (apiserver, KCM and scheduler) down
+	// to zero so the master nodes can be released while the cluster is not in
+	// use. etcd keeps running as its data lives on the etcd nodes. Setting
+	// paused back to false brings the master components back.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// ImagePullSecrets are added to the master, etcd and addon pods, for
+	// pulling component images from private registries.
+	// +optional
+	ImagePullSecrets []v1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// ImageRegistry is a registry mirroring the default images of the control
+	// plane components and addons, e.g. an ECR registry in an air-gapped VPC.
+	// Images keep their repository, public.ecr.aws/eks-distro/etcd-io/etcd is
+	// pulled from <imageRegistry>/eks-distro/etcd-io/etcd. Images set in the
+	// spec are pulled as is.
+	// +optional
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+	// Addons are installed in the guest cluster once its control plane is up.
+	// +optional
+	Addons Addons `json:"addons,omitempty"`
+	// DeletionProtection rejects deleting the ControlPlane until it's set back
+	// to false, for clusters which are expensive to recreate like long running
+	// scale tests.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+	// DeletionPolicy selects which objects are kept when the ControlPlane is
+	// deleted, everything is deleted by default.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// Isolation schedules the etcd and master pods onto a dedicated pool of
+	// management cluster nodes, so one cluster's load test can't starve the
+	// control plane of another.
+	// +optional
+	Isolation *Isolation `json:"isolation,omitempty"`
+	// ArtifactBucket is the S3 bucket and path the cluster's artifacts, like
+	// the results of its load tests, are uploaded to, e.g. my-bucket/kit
+	// without the s3:// scheme. Each cluster uses the <namespace>/<name>
+	// prefix so the bucket can be shared.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9](/.*)?$`
+	// +optional
+	ArtifactBucket string `json:"artifactBucket,omitempty"`
+	// Proxy routes the egress of the apiserver, controller managers and addon
+	// jobs through an HTTP proxy, for VPCs without direct internet access.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// Network sets the address ranges of the cluster, they can't be changed
+	// once the cluster is created.
+	// +optional
+	Network *Network `json:"network,omitempty"`
+}
+
+// DefaultServiceCIDR is the service CIDR of clusters which don't set one
+const DefaultServiceCIDR = "10.96.0.0/12"
+
+// Network has the pod and service CIDRs of the cluster, neither can overlap
+// the other or the VPC CIDR the nodes get their addresses from.
+type Network struct {
+	// PodCIDR is allocated to the nodes by the controller manager, for CNIs
+	// which assign pod addresses from the node's podCIDR. The VPC CNI assigns
+	// VPC addresses and doesn't need one.
+	// +optional
+	PodCIDR string `json:"podCIDR,omitempty"`
+	// ServiceCIDR is the range of the cluster IPs of services, defaults to
+	// 10.96.0.0/12. Its first address is the kubernetes service.
+	// +optional
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+}
+
+// Proxy is set as HTTP_PROXY, HTTPS_PROXY and NO_PROXY in the containers
+type Proxy struct {
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy are hosts, domains and CIDRs reached directly, e.g. the VPC
+	// CIDR for the apiserver to reach the kubelets. Localhost and the
+	// cluster's services are always reached directly.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// Isolation selects the management nodes the control plane pods run on and
+// the priority they run with. PodDisruptionBudgets are created for the etcd
+// and master components of isolated clusters.
+type Isolation struct {
+	// NodePool is the value of the kit.k8s.sh/node-pool label and NoSchedule
+	// taint of the nodes the pods run on.
+	// +kubebuilder:validation:MinLength=1
+	NodePool string `json:"nodePool"`
+	// Priority of the control plane pods, a PriorityClass with this value is
+	// created and shared by the clusters using it, defaults to 1000000.
+	// Values above one billion are reserved for system critical pods.
+	// +kubebuilder:validation:Maximum=1000000000
+	// +optional
+	Priority *int32 `json:"priority,omitempty"`
+}
+
+// DeletionPolicy has a policy for each group of objects of a cluster, e.g.
+// retaining the network and data while deleting compute keeps the endpoint
+// and certificates of the cluster for the next time it's created.
+type DeletionPolicy struct {
+	// Network is the services, including the apiserver load balancer.
+	// +optional
+	Network DeletionPolicyType `json:"network,omitempty"`
+	// Compute is the etcd, master and addon workloads.
+	// +optional
+	Compute DeletionPolicyType `json:"compute,omitempty"`
+	// Data is the config maps and secrets, like certificates and kubeconfigs,
+	// and the etcd volumes.
+	// +optional
+	Data DeletionPolicyType `json:"data,omitempty"`
+}
+
+// DeletionPolicyType is either Delete or Retain
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicyType string
+
+const (
+	DeletionPolicyDelete DeletionPolicyType = "Delete"
+	DeletionPolicyRetain DeletionPolicyType = "Retain"
+)
+
+// Addons lists the addons KIT can install in a cluster
+type Addons struct {
+	// EBSCSIDriver installs the aws-ebs-csi-driver along with a default gp3
+	// StorageClass.
+	// +optional
+	EBSCSIDriver *Addon `json:"ebsCSIDriver,omitempty"`
+	// Monitoring installs Prometheus scraping the apiserver and kubelets, and
+	// Grafana with dashboards of the apiserver and etcd request latencies.
+	// +optional
+	Monitoring *MonitoringAddon `json:"monitoring,omitempty"`
+	// NVIDIADevicePlugin installs the NVIDIA device plugin, so that pods can
+	// request GPUs on nodes with the GPU optimized AMI.
+	// +optional
+	NVIDIADevicePlugin *Addon `json:"nvidiaDevicePlugin,omitempty"`
+	// CSRApprover approves the client and serving certificate requests of
+	// kubelets, so logs and exec work on new nodes without approving them by
+	// hand. Serving certificates need serverTLSBootstrap in the kubelet config.
+	// +optional
+	CSRApprover *Addon `json:"csrApprover,omitempty"`
+	// KubeProxy installs kube-proxy, KIT doesn't install it otherwise.
+	// +optional
+	KubeProxy *KubeProxyAddon `json:"kubeProxy,omitempty"`
+}
+
+// Addon enables an addon
+type Addon struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// KubeProxyMode is the proxy mode of kube-proxy
+// +kubebuilder:validation:Enum=iptables;ipvs;none
+type KubeProxyMode string
+
+const (
+	KubeProxyModeIPTables KubeProxyMode = "iptables"
+	KubeProxyModeIPVS     KubeProxyMode = "ipvs"
+	KubeProxyModeNone     KubeProxyMode = "none"
+)
+
+// KubeProxyAddon enables kube-proxy in one of its modes, for comparing the
+// dataplanes of clusters which only differ by their mode. none doesn't install
+// kube-proxy, for eBPF CNIs like Cilium which replace it. The mode is applied
+// when the addon is installed and can't be changed afterwards.
+type KubeProxyAddon struct {
+	Addon `json:",inline"`
+	// Mode defaults to iptables
+	// +optional
+	Mode KubeProxyMode `json:"mode,omitempty"`
+}
+
+// MonitoringAddon enables the monitoring addon, and optionally remote writes
+// the metrics to a central store
+type MonitoringAddon struct {
+	Addon `json:",inline"`
+	// RemoteWriteURL is an Amazon Managed Prometheus remote write endpoint,
+	// samples are sent with a cluster label set to <namespace>/<name>.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	RemoteWriteURL string `json:"remoteWriteURL,omitempty"`
+	// RemoteWriteMetrics limits the remote written samples to these metric
+	// names, all metrics are sent when empty.
+	// +optional
+	RemoteWriteMetrics []string `json:"remoteWriteMetrics,omitempty"`
+	// RoleARN is the IAM role Prometheus remote writes with, its service
+	// account is annotated for IAM roles for service accounts. The node's
+	// credentials are used when empty.
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/`
+	// +optional
+	RoleARN string `json:"roleARN,omitempty"`
 }
 
 // MasterSpec provides a way for the user to configure master instances and
@@ -55,30 +268,204 @@ type MasterSpec struct {
 	Scheduler         *Component `json:"scheduler,omitempty"`
 	ControllerManager *Component `json:"controllerManager,omitempty"`
 	APIServer         *Component `json:"apiServer,omitempty"`
+	// FeatureGates are passed to the apiserver, KCM and scheduler with
+	// --feature-gates, e.g. {"EphemeralContainers": true}.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+	// RuntimeConfig enables or disables API versions in the apiserver with
+	// --runtime-config, e.g. {"storage.k8s.io/v1alpha1": "true"}.
+	// +optional
+	RuntimeConfig map[string]string `json:"runtimeConfig,omitempty"`
+	// SchedulerConfig is a KubeSchedulerConfiguration passed to the scheduler
+	// with --config, for iterating on scheduling profiles and plugin configs.
+	// +optional
+	SchedulerConfig *SchedulerConfig `json:"schedulerConfig,omitempty"`
+	// CloudProvider selects how the cluster integrates with AWS. aws uses the
+	// in-tree provider in KCM, external runs the AWS cloud controller manager
+	// next to KCM and none, the default, doesn't integrate with a cloud.
+	// +kubebuilder:validation:Enum=external;aws;none
+	// +optional
+	CloudProvider CloudProvider `json:"cloudProvider,omitempty"`
+	// Konnectivity runs the konnectivity server next to the apiserver and its
+	// agents on the worker nodes, so logs, exec and port-forward work when the
+	// nodes aren't reachable from the control plane network. It needs to be
+	// enabled when the cluster is created, as the agent port is added to the
+	// control plane load balancer.
+	// +optional
+	Konnectivity *Konnectivity `json:"konnectivity,omitempty"`
+	// CertSANs are DNS names added to the apiserver serving certificate, e.g.
+	// a Route53 record aliasing the load balancer, so clients connecting with
+	// the name don't get a hostname mismatch. The certificate is reissued
+	// when names are added.
+	// +optional
+	CertSANs []string `json:"certSANs,omitempty"`
+}
+
+// Konnectivity enables tunneling the apiserver to node traffic through
+// apiserver-network-proxy
+type Konnectivity struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// CloudProvider is the cloud provider integration of a cluster
+type CloudProvider string
+
+const (
+	CloudProviderExternal CloudProvider = "external"
+	CloudProviderAWS      CloudProvider = "aws"
+	CloudProviderNone     CloudProvider = "none"
+)
+
+// SchedulerConfig provides a KubeSchedulerConfiguration either inline or from a
+// key in a ConfigMap in the ControlPlane's namespace. The scheduler ignores its
+// flags when --config is set, so clientConnection.kubeconfig in the config
+// needs to be /etc/kubernetes/config/scheduler/scheduler.conf.
+type SchedulerConfig struct {
+	// +optional
+	Inline string `json:"inline,omitempty"`
+	// +optional
+	ConfigMapRef *v1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
 }
 
 // ETCDSpec provides a way to configure the etcd nodes and args which are passed to the etcd process.
 type ETCDSpec struct {
 	Instances `json:",inline"`
-	Spec      *v1.PodSpec `json:"spec,omitempty"`
+	// Image overrides the default etcd image, it can be any image reference
+	// including a tag or digest.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Storage puts the etcd data on a dedicated EBS volume for every member
+	// instead of the node's disk, so disk performance can be tuned for load
+	// tests. It can't be changed once the cluster is created.
+	// +optional
+	Storage *ETCDStorage `json:"storage,omitempty"`
+	Spec    *v1.PodSpec  `json:"spec,omitempty"`
+}
+
+// ETCDStorage configures the EBS volumes of the etcd members, volumes are
+// provisioned by the EBS CSI driver in the management cluster.
+type ETCDStorage struct {
+	// Size of each volume, defaults to 20Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// Type is the EBS volume type, defaults to gp3.
+	// +kubebuilder:validation:Enum=gp3;io1;io2
+	// +optional
+	Type string `json:"type,omitempty"`
+	// IOPS provisioned for gp3, io1 and io2 volumes, the range depends on
+	// the type, 3000-16000 for gp3, 100-64000 for io1 and 100-256000 for io2.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=256000
+	// +optional
+	IOPS int32 `json:"iops,omitempty"`
+	// Throughput in MiB/s provisioned for gp3 volumes.
+	// +kubebuilder:validation:Minimum=125
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	Throughput int32 `json:"throughput,omitempty"`
 }
 
 // Component provides a generic way to pass in args and images to master and etcd
 // components. If a user wants to change the QPS they need to provide the
 // following flag with the desired value -`kube-api-qps:100` in the args.
 type Component struct {
-	Replicas int         `json:"replicas,omitempty"`
-	Spec     *v1.PodSpec `json:"spec,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas int `json:"replicas,omitempty"`
+	// Image overrides the default image of the component, it can be any image
+	// reference including a tag or digest, e.g. a locally built apiserver
+	// pushed to a developer's registry.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// BinaryURL is an HTTP(S) URL of the component binary, e.g. the output of
+	// a Kubernetes CI build in S3. When set, the binary is downloaded when the
+	// pod starts and run in place of the one in the image, so that commits
+	// without a published image can be tested.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	BinaryURL string      `json:"binaryURL,omitempty"`
+	Spec      *v1.PodSpec `json:"spec,omitempty"`
 }
 
 // Instances denotes how the infrastructure of a particular components looks
 // like, if a user wants to use a specific AMI ID, they can provide this in the
 // Instances for the corresponding component.
 type Instances struct {
-	AMI  string `json:"ami,omitempty"`
+	// +kubebuilder:validation:Pattern=`^ami-[0-9a-f]+$`
+	// +optional
+	AMI string `json:"ami,omitempty"`
+	// Type is an EC2 instance type, e.g. m5.xlarge.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9-]+\.[a-z0-9]+$`
+	// +optional
 	Type string `json:"type,omitempty"`
+	// Architecture schedules the pods on nodes of this CPU architecture, e.g.
+	// arm64 to compare Graviton instances with x86. The default images are
+	// multi-arch, overridden images need to be built for the architecture.
+	// +kubebuilder:validation:Enum=amd64;arm64
+	// +optional
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// ServiceCIDR returns the service CIDR of the cluster, or the default
+func (c *ControlPlane) ServiceCIDR() string {
+	if c.Spec.Network == nil || c.Spec.Network.ServiceCIDR == "" {
+		return DefaultServiceCIDR
+	}
+	return c.Spec.Network.ServiceCIDR
+}
+
+// PodCIDR returns the pod CIDR of the cluster, or an empty string when the
+// nodes aren't allocated one
+func (c *ControlPlane) PodCIDR() string {
+	if c.Spec.Network == nil {
+		return ""
+	}
+	return c.Spec.Network.PodCIDR
 }
 
 func (c *ControlPlane) ClusterName() string {
 	return c.Name
 }
+
+// ArtifactsLocation returns the S3 URL the cluster's artifacts are uploaded
+// under, or an empty string when it has no artifact bucket.
+func (c *ControlPlane) ArtifactsLocation() string {
+	if c.Spec.ArtifactBucket == "" {
+		return ""
+	}
+	return fmt.Sprintf("s3://%s/%s/%s", strings.TrimSuffix(c.Spec.ArtifactBucket, "/"), c.Namespace, c.Name)
+}
+
+// AWSClusterName identifies the cluster outside of its namespace, in the
+// kubernetes.io/cluster/<name> tags of the AWS resources its cloud provider
+// creates and in the labels of the nodes its control plane runs on. It's the
+// name recorded in the status when the cluster was first reconciled, as
+// changing it would move etcd to new nodes without its data and orphan the
+// AWS resources tagged with the old name.
+func (c *ControlPlane) AWSClusterName() string {
+	if c.Status.AWSClusterName != "" {
+		return c.Status.AWSClusterName
+	}
+	return c.QualifiedClusterName()
+}
+
+// HasLegacyClusterName is true for clusters provisioned before the cluster
+// name was qualified with the namespace, which keep their bare name
+func (c *ControlPlane) HasLegacyClusterName() bool {
+	return c.Status.AWSClusterName == c.Name
+}
+
+// QualifiedClusterName is the name new clusters are recorded with. The name is
+// suffixed with a hash of the namespace and name, so clusters with the same
+// name in different namespaces don't share AWS resources or nodes. Names too
+// long for a label value are truncated, the hash keeps them unique.
+func (c *ControlPlane) QualifiedClusterName() string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(c.Namespace + "/" + c.Name))
+	suffix := fmt.Sprintf("-%08x", hash.Sum32())
+	name := c.Name
+	if max := validation.LabelValueMaxLength - len(suffix); len(name) > max {
+		name = strings.TrimRight(name[:max], "-.")
+	}
+	return name + suffix
+}
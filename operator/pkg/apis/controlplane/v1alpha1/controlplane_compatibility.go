@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/config"
+	"k8s.io/apimachinery/pkg/util/version"
+	"knative.dev/pkg/apis"
+)
+
+// supportMatrix maps the Kubernetes versions KIT supports to the minimum etcd
+// version they're run with.
+var supportMatrix = map[string]string{
+	"1.18": "3.4",
+	"1.19": "3.4",
+	"1.20": "3.4",
+	"1.21": "3.4",
+}
+
+// SupportedKubernetesVersions returns the Kubernetes versions in the support
+// matrix, in ascending order.
+func SupportedKubernetesVersions() []string {
+	versions := make([]string, 0, len(supportMatrix))
+	for v := range supportMatrix {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.MustParseGeneric(versions[i]).LessThan(version.MustParseGeneric(versions[j]))
+	})
+	return versions
+}
+
+// ValidateCompatibility rejects combinations of the Kubernetes version and the
+// versions of the images the components run, their overridden image or the
+// default image of the operator, which aren't known to work. Images whose tag
+// isn't a version, and components run from a binary URL, can't be checked and
+// are accepted. It's called by the webhook, and while reconciling once a
+// ClusterTemplate has been applied to a version the cluster doesn't run yet.
+func (s *ControlPlaneSpec) ValidateCompatibility() (errs *apis.FieldError) {
+	apiserverImage, apiserverVersion := deployedVersionOf(s.Master.APIServer, config.DefaultAPIServerImage)
+	kubernetesVersion := apiserverVersion
+	if s.KubernetesVersion != "" {
+		if _, ok := supportMatrix[s.KubernetesVersion]; !ok {
+			return apis.ErrInvalidValue(fmt.Sprintf("%s, supported versions are %s",
+				s.KubernetesVersion, strings.Join(SupportedKubernetesVersions(), ", ")), "kubernetesVersion")
+		}
+		kubernetesVersion = version.MustParseGeneric(s.KubernetesVersion)
+		if apiserverVersion != nil && !sameMinor(apiserverVersion, kubernetesVersion) {
+			if s.Master.APIServer != nil && s.Master.APIServer.Image != "" {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, must be kubernetes %s",
+					apiserverImage, s.KubernetesVersion), "master.apiServer.image"))
+			} else {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, the default apiserver image %s is kubernetes %d.%d, override master.apiServer.image to run another version",
+					s.KubernetesVersion, apiserverImage, apiserverVersion.Major(), apiserverVersion.Minor()), "kubernetesVersion"))
+			}
+		}
+	}
+	if apiserverVersion == nil {
+		apiserverVersion = kubernetesVersion
+	}
+	if kubernetesVersion != nil {
+		if minEtcd, ok := supportMatrix[fmt.Sprintf("%d.%d", kubernetesVersion.Major(), kubernetesVersion.Minor())]; ok {
+			etcdImage := s.Etcd.Image
+			if etcdImage == "" {
+				etcdImage = config.DefaultEtcdImage
+			}
+			if v := versionOf(etcdImage); v != nil && v.LessThan(version.MustParseGeneric(minEtcd)) {
+				errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, kubernetes %d.%d requires etcd %s or later",
+					etcdImage, kubernetesVersion.Major(), kubernetesVersion.Minor(), minEtcd), "etcd.image"))
+			}
+		}
+	}
+	if apiserverVersion == nil {
+		return errs
+	}
+	// The controller manager and scheduler can't be newer than the apiserver,
+	// and can be at most one minor version older.
+	for _, component := range []struct {
+		field        string
+		spec         *Component
+		defaultImage string
+	}{
+		{"master.controllerManager.image", s.Master.ControllerManager, config.DefaultControllerManagerImage},
+		{"master.scheduler.image", s.Master.Scheduler, config.DefaultSchedulerImage},
+	} {
+		image, v := deployedVersionOf(component.spec, component.defaultImage)
+		if v == nil {
+			continue
+		}
+		if v.Major() != apiserverVersion.Major() || v.Minor() > apiserverVersion.Minor() || v.Minor()+1 < apiserverVersion.Minor() {
+			errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, must be within one minor version older than the apiserver %d.%d",
+				image, apiserverVersion.Major(), apiserverVersion.Minor()), component.field))
+		}
+	}
+	return errs
+}
+
+// deployedVersionOf returns the image a component runs and its version, or a
+// nil version when it can't be known
+func deployedVersionOf(component *Component, defaultImage string) (string, *version.Version) {
+	if component == nil {
+		return defaultImage, versionOf(defaultImage)
+	}
+	if component.BinaryURL != "" {
+		return component.BinaryURL, nil
+	}
+	if component.Image != "" {
+		return component.Image, versionOf(component.Image)
+	}
+	return defaultImage, versionOf(defaultImage)
+}
+
+// versionOf returns the version in the tag of an image, or nil when the image
+// isn't tagged with a version, e.g. public.ecr.aws/eks-distro/etcd-io/etcd:v3.4.14-eks-1-18-1
+func versionOf(image string) *version.Version {
+	image = strings.SplitN(image, "@", 2)[0]
+	i := strings.LastIndex(image, ":")
+	if i == -1 || i < strings.LastIndex(image, "/") {
+		return nil
+	}
+	v, err := version.ParseGeneric(image[i+1:])
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func sameMinor(a, b *version.Version) bool {
+	return a.Major() == b.Major() && a.Minor() == b.Minor()
+}
@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// ConvertTo converts the ControlPlane to another version. v1alpha1 is the
+// storage version and the hub every other version converts through. The
+// v1beta1 schema is the same as v1alpha1 for now, so the spec and status are
+// copied field for field through their JSON representation. Fields which are
+// renamed or restructured in v1beta1 need to be converted explicitly here and
+// in ConvertFrom.
+func (c *ControlPlane) ConvertTo(_ context.Context, to apis.Convertible) error {
+	switch sink := to.(type) {
+	case *v1beta1.ControlPlane:
+		sink.ObjectMeta = c.ObjectMeta
+		if err := convert(c.Spec, &sink.Spec); err != nil {
+			return fmt.Errorf("converting spec, %w", err)
+		}
+		if err := convert(c.Status, &sink.Status); err != nil {
+			return fmt.Errorf("converting status, %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got %T", sink)
+	}
+}
+
+// ConvertFrom converts a ControlPlane of another version to v1alpha1
+func (c *ControlPlane) ConvertFrom(_ context.Context, from apis.Convertible) error {
+	switch source := from.(type) {
+	case *v1beta1.ControlPlane:
+		c.ObjectMeta = source.ObjectMeta
+		if err := convert(source.Spec, &c.Spec); err != nil {
+			return fmt.Errorf("converting spec, %w", err)
+		}
+		if err := convert(source.Status, &c.Status); err != nil {
+			return fmt.Errorf("converting status, %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got %T", source)
+	}
+}
+
+func convert(from, to interface{}) error {
+	raw, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, to)
+}
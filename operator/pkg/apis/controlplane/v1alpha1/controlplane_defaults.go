@@ -30,7 +30,9 @@ func (c *ControlPlane) SetDefaults(ctx context.Context) {
 
 // SetDefaults for the ControlPlaneSpec, cascading to all subspecs
 func (s *ControlPlaneSpec) SetDefaults(ctx context.Context) {
-	if s.KubernetesVersion == "" {
+	// The version is inherited from the template when there is one, it gets
+	// defaulted after the template is applied while reconciling.
+	if s.KubernetesVersion == "" && s.Template == "" {
 		s.KubernetesVersion = config.DefaultKubernetesVersion
 	}
 	if s.Master.APIServer == nil {
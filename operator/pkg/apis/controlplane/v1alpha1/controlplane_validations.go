@@ -16,11 +16,209 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
 )
 
+// DeletionConfirmationAnnotationKey is set to the name of the ControlPlane to
+// confirm its deletion, when the webhook requires a confirmation.
+const DeletionConfirmationAnnotationKey = "kit.k8s.sh/confirm-deletion"
+
+// MaxNameLength is the longest ControlPlane name for which the names of the
+// objects created for it are valid, the longest being the
+// <name>-controlplane-endpoint-port Service port name limited to 63 characters.
+const MaxNameLength = 36
+
 func (c *ControlPlane) Validate(ctx context.Context) (errs *apis.FieldError) {
-	// TODO
+	// Status updates don't change the spec, rejecting them for a rule added
+	// after the ControlPlane was created would block it from being reconciled
+	if apis.IsInStatusUpdate(ctx) {
+		return nil
+	}
+	// The name can't change, it's only checked on create so ControlPlanes
+	// created before the check can still be updated and finalized
+	if apis.IsInCreate(ctx) {
+		errs = errs.Also(validateName(c.Name).ViaField("metadata"))
+	}
+	errs = errs.Also(c.Spec.validate(ctx).ViaField("spec"))
+	// Compatibility depends on the default images of the operator, which change
+	// with its releases, so it's only checked when the spec changes. A version
+	// which didn't change isn't checked against the images, ControlPlanes
+	// defaulted to an older version than the default images can still be
+	// paused or have their other fields changed.
+	if original, ok := apis.GetBaseline(ctx).(*ControlPlane); !ok || !equality.Semantic.DeepEqual(original.Spec, c.Spec) {
+		spec := c.Spec.DeepCopy()
+		if ok && original.Spec.KubernetesVersion == spec.KubernetesVersion {
+			spec.KubernetesVersion = ""
+		}
+		errs = errs.Also(spec.ValidateCompatibility().ViaField("spec"))
+	}
+	if original, ok := apis.GetBaseline(ctx).(*ControlPlane); ok && apis.IsInUpdate(ctx) {
+		errs = errs.Also(
+			c.validateNetworkUpdate(original).ViaField("spec", "network"),
+			c.Spec.Etcd.validateUpdate(&original.Spec.Etcd).ViaField("spec", "etcd"),
+		)
+	}
+	return errs
+}
+
+// validateNetworkUpdate rejects changing the CIDRs of a cluster, the nodes and
+// services keep the addresses they were given from the original ones.
+func (c *ControlPlane) validateNetworkUpdate(original *ControlPlane) (errs *apis.FieldError) {
+	if c.PodCIDR() != original.PodCIDR() {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("pod CIDR can't be changed from %q", original.PodCIDR()), "podCIDR"))
+	}
+	if c.ServiceCIDR() != original.ServiceCIDR() {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("service CIDR can't be changed from %q", original.ServiceCIDR()), "serviceCIDR"))
+	}
+	return errs
+}
+
+// validateName rejects names which would fail once derived into the names of
+// Services, ports and labels, instead of deep inside a reconcile.
+func validateName(name string) (errs *apis.FieldError) {
+	if len(name) > MaxNameLength {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, must be no more than %d characters", name, MaxNameLength), "name"))
+	}
+	if msgs := validation.IsDNS1035Label(name); len(msgs) > 0 {
+		errs = errs.Also(apis.ErrInvalidValue(fmt.Sprintf("%s, %s", name, strings.Join(msgs, ", ")), "name"))
+	}
+	return errs
+}
+
+func (s *ControlPlaneSpec) validate(ctx context.Context) (errs *apis.FieldError) {
+	errs = errs.Also(
+		s.Master.validate(ctx).ViaField("master"),
+		s.Etcd.validate(ctx).ViaField("etcd"),
+	)
+	// Clusters without a network get the defaults, which are checked the same
+	network := &Network{}
+	if s.Network != nil {
+		network = s.Network
+	}
+	errs = errs.Also(network.validate(ctx).ViaField("network"))
+	return errs
+}
+
+func (m *MasterSpec) validate(ctx context.Context) (errs *apis.FieldError) {
+	if m.SchedulerConfig != nil && m.SchedulerConfig.Inline != "" && m.SchedulerConfig.ConfigMapRef != nil {
+		errs = errs.Also(apis.ErrMultipleOneOf("inline", "configMapRef").ViaField("schedulerConfig"))
+	}
+	for i, name := range m.CertSANs {
+		validate := validation.IsDNS1123Subdomain
+		if strings.HasPrefix(name, "*.") {
+			validate = validation.IsWildcardDNS1123Subdomain
+		}
+		if msgs := validate(name); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidArrayValue(fmt.Sprintf("%s, %s", name, strings.Join(msgs, ", ")), "certSANs", i))
+		}
+	}
+	return errs
+}
+
+func (n *Network) validate(_ context.Context) (errs *apis.FieldError) {
+	var podCIDR, serviceCIDR *net.IPNet
+	if n.PodCIDR != "" {
+		if podCIDR, errs = parseCIDR(n.PodCIDR, "podCIDR"); errs != nil {
+			return errs
+		}
+	}
+	// The default is checked as well, the pod CIDR can't overlap it either
+	serviceCIDRValue := n.ServiceCIDR
+	if serviceCIDRValue == "" {
+		serviceCIDRValue = DefaultServiceCIDR
+	}
+	if serviceCIDR, errs = parseCIDR(serviceCIDRValue, "serviceCIDR"); errs != nil {
+		return errs
+	}
+	// The apiserver refuses to allocate from more than 2^20 addresses
+	if ones, _ := serviceCIDR.Mask.Size(); ones < 12 {
+		return apis.ErrInvalidValue(fmt.Sprintf("%s, must be a /12 or smaller", serviceCIDRValue), "serviceCIDR")
+	}
+	if podCIDR != nil && Overlap(podCIDR, serviceCIDR) {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("pod CIDR %s overlaps service CIDR %s", n.PodCIDR, serviceCIDRValue), "podCIDR", "serviceCIDR"))
+	}
+	return errs
+}
+
+// parseCIDR parses an IPv4 CIDR, rejecting addresses with host bits set like
+// 10.96.0.1/12 as it's unclear which range was meant
+func parseCIDR(cidr string, field string) (*net.IPNet, *apis.FieldError) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, apis.ErrInvalidValue(fmt.Sprintf("%s, %v", cidr, err), field)
+	}
+	if ip.To4() == nil {
+		return nil, apis.ErrInvalidValue(fmt.Sprintf("%s, must be an IPv4 CIDR", cidr), field)
+	}
+	if !ip.Equal(ipNet.IP) {
+		return nil, apis.ErrInvalidValue(fmt.Sprintf("%s, did you mean %s", cidr, ipNet), field)
+	}
+	return ipNet, nil
+}
+
+// Overlap returns true if the CIDRs have addresses in common, one of two CIDRs
+// which overlap contains the other's first address.
+func Overlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// ValidateDelete rejects deleting a ControlPlane with deletion protection, set
+// on it or on its ClusterTemplate, and when a confirmation is required, one
+// which isn't annotated with its name. The template is nil when the
+// ControlPlane has none or it doesn't exist.
+func (c *ControlPlane) ValidateDelete(template *ClusterTemplate, requireConfirmation bool) error {
+	if c.Spec.DeletionProtection {
+		return fmt.Errorf("control plane %s has deletion protection, set spec.deletionProtection to false to delete it", c.Name)
+	}
+	if template != nil && template.Spec.ControlPlane.DeletionProtection {
+		return fmt.Errorf("control plane %s has deletion protection from cluster template %s, set the template's spec.controlPlane.deletionProtection to false to delete it", c.Name, template.Name)
+	}
+	if requireConfirmation && c.Annotations[DeletionConfirmationAnnotationKey] != c.Name {
+		return fmt.Errorf("deleting control plane %s requires the %s annotation set to its name", c.Name, DeletionConfirmationAnnotationKey)
+	}
 	return nil
 }
+
+func (e *ETCDSpec) validate(ctx context.Context) (errs *apis.FieldError) {
+	if e.Storage == nil {
+		return nil
+	}
+	return e.Storage.validate(ctx).ViaField("storage")
+}
+
+// validateUpdate rejects changing the storage of a cluster, the volume claim
+// templates of the etcd StatefulSet can't be changed once it's created.
+func (e *ETCDSpec) validateUpdate(original *ETCDSpec) (errs *apis.FieldError) {
+	if !equality.Semantic.DeepEqual(e.Storage, original.Storage) {
+		errs = errs.Also(apis.ErrGeneric("storage can't be changed once the cluster is created", "storage"))
+	}
+	return errs
+}
+
+// iopsRanges are the IOPS which can be provisioned for each EBS volume type,
+// the CRD schema only enforces the widest range as it can't refer to the type.
+var iopsRanges = map[string][2]int32{
+	"gp3": {3000, 16000},
+	"io1": {100, 64000},
+	"io2": {100, 256000},
+}
+
+func (s *ETCDStorage) validate(_ context.Context) (errs *apis.FieldError) {
+	volumeType := s.Type
+	if volumeType == "" {
+		volumeType = "gp3"
+	}
+	if r, ok := iopsRanges[volumeType]; ok && s.IOPS != 0 && (s.IOPS < r[0] || s.IOPS > r[1]) {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(s.IOPS, r[0], r[1], "iops"))
+	}
+	if s.Throughput != 0 && volumeType != "gp3" {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("throughput can only be provisioned for gp3 volumes, got %s", volumeType), "throughput"))
+	}
+	return errs
+}
@@ -30,7 +30,10 @@ var (
 	// APIVersion is the current API version used to register these objects
 	APIVersion = "v1alpha1"
 
-	ControlPlaneKind = "ControlPlane"
+	ControlPlaneKind    = "ControlPlane"
+	ClusterTemplateKind = "ClusterTemplate"
+	ClusterSetKind      = "ClusterSet"
+	LoadTestKind        = "LoadTest"
 	// SchemeGroupVersion is group version used to register these objects
 	SchemeGroupVersion = schema.GroupVersion{Group: "kit.k8s.sh", Version: APIVersion}
 
@@ -42,6 +45,7 @@ var (
 
 	Resources = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
 		SchemeGroupVersion.WithKind(ControlPlaneKind): &ControlPlane{},
+		SchemeGroupVersion.WithKind(ClusterSetKind):   &ClusterSet{},
 	}
 )
 
@@ -50,8 +54,24 @@ const (
 	// controller is able to take actions: it's correctly configured, can make
 	// necessary API calls, and isn't disabled.
 	Active apis.ConditionType = "Active"
+	// Stalled is set on resources which have been waiting on a dependency for
+	// longer than the stall timeout, the message names the dependency.
+	Stalled apis.ConditionType = "Stalled"
+	// WaitingOnQuota is set on control planes which can't be provisioned until
+	// an increase of an account quota, which has been requested, is approved.
+	WaitingOnQuota apis.ConditionType = "WaitingOnQuota"
+	// ControlPlaneReady is true when the last probe of the guest apiserver
+	// through its endpoint passed, the message has the check which failed.
+	ControlPlaneReady apis.ConditionType = "ControlPlaneReady"
+	// ScaleDownBlocked is set on cluster sets with members above the desired
+	// replicas which can't be deleted, the message has their deletion
+	// protection.
+	ScaleDownBlocked apis.ConditionType = "ScaleDownBlocked"
 )
 
 func init() {
 	SchemeBuilder.Register(&ControlPlane{}, &ControlPlaneList{})
+	SchemeBuilder.Register(&ClusterTemplate{}, &ClusterTemplateList{})
+	SchemeBuilder.Register(&ClusterSet{}, &ClusterSetList{})
+	SchemeBuilder.Register(&LoadTest{}, &LoadTestList{})
 }
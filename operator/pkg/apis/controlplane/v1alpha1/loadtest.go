@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+// LoadTest is the Schema for the LoadTests API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=lt
+// +kubebuilder:printcolumn:name="ControlPlane",type="string",JSONPath=".spec.controlPlane"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type LoadTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LoadTestSpec   `json:"spec,omitempty"`
+	Status LoadTestStatus `json:"status,omitempty"`
+}
+
+// LoadTestList contains a list of LoadTest
+// +kubebuilder:object:root=true
+type LoadTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LoadTest `json:"items"`
+}
+
+// LoadTestSpec runs clusterloader2 once against a KIT cluster. The test runs
+// in a Job next to the ControlPlane using the cluster's admin kubeconfig, and
+// the report directory is uploaded to S3 when a bucket is provided.
+type LoadTestSpec struct {
+	// ControlPlane is the name of the ControlPlane in the same namespace to run
+	// the test against.
+	// +kubebuilder:validation:MinLength=1
+	ControlPlane string `json:"controlPlane"`
+	// Config is the clusterloader2 test config from kubernetes/perf-tests to
+	// run, defaults to load.
+	// +kubebuilder:validation:Enum=density;load
+	// +optional
+	Config string `json:"config,omitempty"`
+	// Nodes is the number of data plane nodes in the cluster under test.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Nodes int32 `json:"nodes,omitempty"`
+	// Overrides are passed to clusterloader2 as test overrides, for example
+	// PODS_PER_NODE or CL2_LOAD_TEST_THROUGHPUT.
+	// +optional
+	Overrides map[string]string `json:"overrides,omitempty"`
+	// Image is the clusterloader2 image, the binary is expected at
+	// /clusterloader next to a shell.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// PerfTestsRef is the commit or tag of kubernetes/perf-tests the test
+	// config is read from, it should match the clusterloader2 image. Defaults
+	// to the commit the default image is built from.
+	// +optional
+	PerfTestsRef string `json:"perfTestsRef,omitempty"`
+	// ResultsBucket is the S3 bucket and path the clusterloader2 reports are
+	// uploaded to, e.g. my-bucket/kit without the s3:// scheme. Reports are
+	// uploaded to the artifact bucket of the ControlPlane when it's empty.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9](/.*)?$`
+	// +optional
+	ResultsBucket string `json:"resultsBucket,omitempty"`
+}
+
+// LoadTestPhase is the lifecycle of a LoadTest
+type LoadTestPhase string
+
+const (
+	LoadTestRunning   LoadTestPhase = "Running"
+	LoadTestSucceeded LoadTestPhase = "Succeeded"
+	LoadTestFailed    LoadTestPhase = "Failed"
+)
+
+// LoadTestStatus defines the observed state of the LoadTest
+type LoadTestStatus struct {
+	// Conditions is the set of conditions required for this LoadTest to run,
+	// and indicates whether or not those conditions are met.
+	// +optional
+	Conditions apis.Conditions `json:"conditions,omitempty"`
+	// Phase is Running until clusterloader2 exits, it is Succeeded when all
+	// the SLOs measured by the test config were met and Failed otherwise.
+	// +optional
+	Phase LoadTestPhase `json:"phase,omitempty"`
+	// StartTime is when the test started running.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when the test finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Results is where the clusterloader2 reports were uploaded.
+	// +optional
+	Results string `json:"results,omitempty"`
+	// Summary is read from the junit report of clusterloader2 once the test
+	// finished, it's empty when the report couldn't be read.
+	// +optional
+	Summary *LoadTestSummary `json:"summary,omitempty"`
+}
+
+// LoadTestSummary counts the tests of a clusterloader2 run, each measurement
+// and phase of the test config is a test which fails when its SLO isn't met.
+type LoadTestSummary struct {
+	Tests    int32 `json:"tests"`
+	Failures int32 `json:"failures"`
+}
+
+func (l *LoadTest) StatusConditions() apis.ConditionManager {
+	return apis.NewLivingConditionSet(
+		Active,
+	).Manage(l)
+}
+
+func (l *LoadTest) GetConditions() apis.Conditions {
+	return l.Status.Conditions
+}
+
+func (l *LoadTest) SetConditions(conditions apis.Conditions) {
+	l.Status.Conditions = conditions
+}
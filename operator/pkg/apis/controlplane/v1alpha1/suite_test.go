@@ -0,0 +1,400 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1beta1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "v1alpha1")
+}
+
+var _ = Describe("Conversion", func() {
+	var controlPlane *v1alpha1.ControlPlane
+	BeforeEach(func() {
+		size := resource.MustParse("50Gi")
+		controlPlane = &v1alpha1.ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "testcluster", Namespace: "default", Labels: map[string]string{"team": "scale"}},
+			Spec: v1alpha1.ControlPlaneSpec{
+				KubernetesVersion: "1.20",
+				Master: v1alpha1.MasterSpec{
+					Instances:     v1alpha1.Instances{Type: "m5.xlarge", Architecture: "arm64"},
+					APIServer:     &v1alpha1.Component{Replicas: 3, Image: "apiserver:dev", Spec: &v1.PodSpec{HostNetwork: true}},
+					FeatureGates:  map[string]bool{"EphemeralContainers": true},
+					CloudProvider: v1alpha1.CloudProviderExternal,
+					Konnectivity:  &v1alpha1.Konnectivity{Enabled: true},
+				},
+				Etcd: v1alpha1.ETCDSpec{
+					Image:   "etcd:dev",
+					Storage: &v1alpha1.ETCDStorage{Size: &size, Type: "io2", IOPS: 10000},
+				},
+				Addons:         v1alpha1.Addons{Monitoring: &v1alpha1.MonitoringAddon{Addon: v1alpha1.Addon{Enabled: true}, RemoteWriteMetrics: []string{"up"}}},
+				DeletionPolicy: v1alpha1.DeletionPolicy{Data: v1alpha1.DeletionPolicyRetain},
+				Isolation:      &v1alpha1.Isolation{NodePool: "tenant-a", Priority: aws.Int32(5000)},
+			},
+			Status: v1alpha1.ControlPlaneStatus{
+				Conditions: apis.Conditions{{Type: v1alpha1.Active, Status: v1.ConditionTrue}},
+				Ready:      true,
+				Version:    "1.20",
+				Etcd:       &v1alpha1.ETCDStatus{Healthy: true, Members: []v1alpha1.ETCDMember{{ID: "1", Name: "etcd-0"}}},
+			},
+		}
+	})
+	It("should round trip a ControlPlane through v1beta1", func() {
+		converted := &v1beta1.ControlPlane{}
+		Expect(controlPlane.ConvertTo(context.Background(), converted)).To(Succeed())
+		Expect(converted.Name).To(Equal(controlPlane.Name))
+		Expect(converted.Spec.Etcd.Storage.IOPS).To(BeNumerically("==", 10000))
+		Expect(converted.Spec.Master.CloudProvider).To(Equal(v1beta1.CloudProviderExternal))
+		roundTripped := &v1alpha1.ControlPlane{}
+		Expect(roundTripped.ConvertFrom(context.Background(), converted)).To(Succeed())
+		Expect(roundTripped).To(Equal(controlPlane))
+	})
+	It("should round trip every field through v1beta1", func() {
+		size := resource.MustParse("100Gi")
+		now := metav1.NewTime(time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC).Local())
+		podSpecFor := func(name string) *v1.PodSpec {
+			return &v1.PodSpec{
+				Containers: []v1.Container{{
+					Name:      name,
+					Args:      []string{"--v=4"},
+					Env:       []v1.EnvVar{{Name: "GOMAXPROCS", Value: "4"}},
+					Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}},
+				}},
+				NodeSelector: map[string]string{"kit.k8s.sh/app": name},
+				Tolerations:  []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}},
+				HostNetwork:  true,
+			}
+		}
+		component := func(name string) *v1alpha1.Component {
+			return &v1alpha1.Component{Replicas: 3, Image: name + ":dev", BinaryURL: "https://artifacts.example.com/" + name, Spec: podSpecFor(name)}
+		}
+		controlPlane = &v1alpha1.ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "testcluster", Namespace: "default", Labels: map[string]string{"team": "scale"}},
+			Spec: v1alpha1.ControlPlaneSpec{
+				Template:          "large",
+				KubernetesVersion: "1.20",
+				Master: v1alpha1.MasterSpec{
+					Instances:         v1alpha1.Instances{AMI: "ami-0123456789abcdef0", Type: "m5.xlarge", Architecture: "arm64"},
+					Scheduler:         component("kube-scheduler"),
+					ControllerManager: component("kube-controller-manager"),
+					APIServer:         component("kube-apiserver"),
+					FeatureGates:      map[string]bool{"EphemeralContainers": true},
+					RuntimeConfig:     map[string]string{"api/alpha": "true"},
+					SchedulerConfig: &v1alpha1.SchedulerConfig{
+						Inline:       "apiVersion: kubescheduler.config.k8s.io/v1beta1",
+						ConfigMapRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "scheduler"}, Key: "config.yaml"},
+					},
+					CloudProvider: v1alpha1.CloudProviderExternal,
+					Konnectivity:  &v1alpha1.Konnectivity{Enabled: true},
+					CertSANs:      []string{"api.example.com"},
+				},
+				Etcd: v1alpha1.ETCDSpec{
+					Instances: v1alpha1.Instances{AMI: "ami-0123456789abcdef1", Type: "m5.large", Architecture: "amd64"},
+					Image:     "etcd:dev",
+					Storage:   &v1alpha1.ETCDStorage{Size: &size, Type: "gp3", IOPS: 6000, Throughput: 500},
+					Spec:      podSpecFor("etcd"),
+				},
+				Paused:           true,
+				ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+				ImageRegistry:    "123456789012.dkr.ecr.us-west-2.amazonaws.com",
+				Addons: v1alpha1.Addons{
+					EBSCSIDriver: &v1alpha1.Addon{Enabled: true},
+					Monitoring: &v1alpha1.MonitoringAddon{
+						Addon:              v1alpha1.Addon{Enabled: true},
+						RemoteWriteURL:     "https://aps-workspaces.us-west-2.amazonaws.com/workspaces/ws-1234/api/v1/remote_write",
+						RemoteWriteMetrics: []string{"up"},
+						RoleARN:            "arn:aws:iam::123456789012:role/prometheus",
+					},
+					NVIDIADevicePlugin: &v1alpha1.Addon{Enabled: true},
+					CSRApprover:        &v1alpha1.Addon{Enabled: true},
+					KubeProxy:          &v1alpha1.KubeProxyAddon{Addon: v1alpha1.Addon{Enabled: true}, Mode: v1alpha1.KubeProxyModeIPVS},
+				},
+				DeletionProtection: true,
+				DeletionPolicy:     v1alpha1.DeletionPolicy{Network: v1alpha1.DeletionPolicyRetain, Compute: v1alpha1.DeletionPolicyDelete, Data: v1alpha1.DeletionPolicyRetain},
+				Isolation:          &v1alpha1.Isolation{NodePool: "tenant-a", Priority: aws.Int32(5000)},
+				ArtifactBucket:     "my-bucket/kit",
+				Proxy:              &v1alpha1.Proxy{HTTPProxy: "http://proxy.corp:3128", HTTPSProxy: "http://proxy.corp:3128", NoProxy: []string{"10.0.0.0/16"}},
+				Network:            &v1alpha1.Network{PodCIDR: "192.168.0.0/16", ServiceCIDR: "10.100.0.0/16"},
+			},
+			Status: v1alpha1.ControlPlaneStatus{
+				Conditions: apis.Conditions{{
+					Type: v1alpha1.Active, Status: v1.ConditionFalse, Severity: apis.ConditionSeverityWarning,
+					LastTransitionTime: apis.VolatileTime{Inner: now}, Reason: "EtcdUnhealthy", Message: "etcd has no leader",
+				}},
+				EstimatedHourlyCost:         "$1.23",
+				Endpoint:                    "https://elb-endpoint:443",
+				Artifacts:                   "s3://my-bucket/kit/default/testcluster",
+				Ready:                       true,
+				Initialized:                 true,
+				ExternalManagedControlPlane: true,
+				Version:                     "1.20",
+				AWSClusterName:              "default-testcluster",
+				Etcd: &v1alpha1.ETCDStatus{
+					Healthy:            true,
+					Members:            []v1alpha1.ETCDMember{{ID: "1", Name: "etcd-0", ClientURLs: []string{"https://etcd-0:2379"}}},
+					Leader:             "etcd-0",
+					DBSizeBytes:        1 << 20,
+					Alarms:             []string{"NOSPACE"},
+					Message:            "database space exceeded",
+					LastTransitionTime: now,
+				},
+				Provisioning: map[string]metav1.Duration{"etcd": {Duration: time.Minute}},
+				Manifests:    map[string]v1alpha1.ManifestStatus{"kube-proxy": {Hash: "1234", LastChanged: &now, LastChange: "kube-proxy.yaml"}},
+				Guest:        &v1alpha1.GuestStatus{Nodes: 3, ReadyNodes: 2},
+				Availability: &v1alpha1.AvailabilityStatus{
+					Since: now, LastProbeTime: &now, UpSeconds: 3600, DownSeconds: 60, Uptime: "98.36%",
+					Incidents: []v1alpha1.Incident{{Start: now, End: &now, Check: "readyz", Message: "etcd timed out"}},
+				},
+				History: []v1alpha1.HistoryEntry{{Time: now, Reason: "Upgraded", Message: "1.19 to 1.20"}},
+			},
+		}
+		expectFilled(reflect.ValueOf(controlPlane.Spec), "spec")
+		expectFilled(reflect.ValueOf(controlPlane.Status), "status")
+		converted := &v1beta1.ControlPlane{}
+		Expect(controlPlane.ConvertTo(context.Background(), converted)).To(Succeed())
+		roundTripped := &v1alpha1.ControlPlane{}
+		Expect(roundTripped.ConvertFrom(context.Background(), converted)).To(Succeed())
+		Expect(roundTripped).To(Equal(controlPlane))
+	})
+	It("should fail to convert to an unknown version", func() {
+		Expect(controlPlane.ConvertTo(context.Background(), &v1alpha1.ControlPlane{})).ToNot(Succeed())
+	})
+})
+
+// expectFilled fails on the zero fields of the v1alpha1 types, so that new
+// fields can't be left out of the round trip
+func expectFilled(value reflect.Value, path string) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		Expect(value.IsNil()).To(BeFalse(), "%s is not set", path)
+		expectFilled(value.Elem(), path)
+	case reflect.Struct:
+		if value.Type().PkgPath() != reflect.TypeOf(v1alpha1.ControlPlane{}).PkgPath() {
+			Expect(value.IsZero()).To(BeFalse(), "%s is not set", path)
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			expectFilled(value.Field(i), path+"."+value.Type().Field(i).Name)
+		}
+	case reflect.Slice, reflect.Map:
+		Expect(value.Len()).ToNot(BeZero(), "%s is not set", path)
+	default:
+		Expect(value.IsZero()).To(BeFalse(), "%s is not set", path)
+	}
+}
+
+var _ = Describe("Validation", func() {
+	var controlPlane *v1alpha1.ControlPlane
+	BeforeEach(func() {
+		controlPlane = &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "testcluster", Namespace: "default"}}
+	})
+	It("should accept IOPS in the range of the volume type", func() {
+		controlPlane.Spec.Etcd.Storage = &v1alpha1.ETCDStorage{Type: "io2", IOPS: 100000}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should reject IOPS outside the range of the volume type", func() {
+		controlPlane.Spec.Etcd.Storage = &v1alpha1.ETCDStorage{IOPS: 100}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.etcd.storage.iops"))
+	})
+	It("should reject throughput for volume types other than gp3", func() {
+		controlPlane.Spec.Etcd.Storage = &v1alpha1.ETCDStorage{Type: "io1", Throughput: 500}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.etcd.storage.throughput"))
+	})
+	It("should reject unsupported kubernetes versions", func() {
+		controlPlane.Spec.KubernetesVersion = "1.12"
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.kubernetesVersion"))
+	})
+	It("should reject etcd versions older than the kubernetes version requires", func() {
+		controlPlane.Spec.KubernetesVersion = "1.20"
+		controlPlane.Spec.Etcd.Image = "quay.io/coreos/etcd:v3.3.25"
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.etcd.image"))
+	})
+	It("should reject a scheduler newer than the apiserver", func() {
+		controlPlane.Spec.KubernetesVersion = "1.20"
+		controlPlane.Spec.Master.Scheduler = &v1alpha1.Component{Image: "public.ecr.aws/eks-distro/kubernetes/kube-scheduler:v1.21.2-eks-1-21-4"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.master.scheduler.image"))
+	})
+	It("should accept a controller manager one minor version older than the apiserver", func() {
+		controlPlane.Spec.KubernetesVersion = "1.20"
+		controlPlane.Spec.Master.ControllerManager = &v1alpha1.Component{Image: "public.ecr.aws/eks-distro/kubernetes/kube-controller-manager:v1.19.8-eks-1-19-4"}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should accept images which aren't tagged with a version", func() {
+		controlPlane.Spec.KubernetesVersion = "1.20"
+		controlPlane.Spec.Etcd.Image = "localhost:5000/etcd:latest"
+		controlPlane.Spec.Master.APIServer = &v1alpha1.Component{Image: "localhost:5000/kube-apiserver"}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should reject kubernetes versions which the default images don't run", func() {
+		controlPlane.Spec.KubernetesVersion = "1.19"
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.kubernetesVersion"))
+	})
+	It("should reject a default controller manager newer than an overridden apiserver", func() {
+		controlPlane.Spec.KubernetesVersion = "1.19"
+		controlPlane.Spec.Master.APIServer = &v1alpha1.Component{Image: "public.ecr.aws/eks-distro/kubernetes/kube-apiserver:v1.19.8-eks-1-19-4"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.master.controllerManager.image"))
+	})
+	It("should accept updates which don't change the spec of incompatible ControlPlanes", func() {
+		controlPlane.Spec.KubernetesVersion = "1.19"
+		original := controlPlane.DeepCopy()
+		controlPlane.Labels = map[string]string{"kit.k8s.sh/test": "true"}
+		Expect(controlPlane.Validate(apis.WithinUpdate(context.Background(), original))).To(BeNil())
+	})
+	It("should accept updates of ControlPlanes defaulted to a version older than the default images", func() {
+		controlPlane.Spec.KubernetesVersion = "1.19"
+		original := controlPlane.DeepCopy()
+		controlPlane.Spec.Paused = true
+		Expect(controlPlane.Validate(apis.WithinUpdate(context.Background(), original))).To(BeNil())
+		controlPlane.Spec.KubernetesVersion = "1.18"
+		Expect(controlPlane.Validate(apis.WithinUpdate(context.Background(), original)).Error()).To(ContainSubstring("spec.kubernetesVersion"))
+	})
+	It("should reject names longer than the max length", func() {
+		controlPlane.Name = strings.Repeat("a", v1alpha1.MaxNameLength+1)
+		Expect(controlPlane.Validate(apis.WithinCreate(context.Background())).Error()).To(ContainSubstring("metadata.name"))
+	})
+	It("should accept updates of ControlPlanes created before the name was checked", func() {
+		controlPlane.Name = strings.Repeat("a", v1alpha1.MaxNameLength+1)
+		original := controlPlane.DeepCopy()
+		controlPlane.Finalizers = []string{"kit.k8s.sh/test"}
+		Expect(controlPlane.Validate(apis.WithinUpdate(context.Background(), original))).To(BeNil())
+	})
+	It("should accept status updates", func() {
+		controlPlane.Name = "1test.cluster"
+		controlPlane.Spec.Master.CertSANs = []string{"https://api.example.com"}
+		Expect(controlPlane.Validate(apis.WithinSubResourceUpdate(context.Background(), controlPlane.DeepCopy(), "status"))).To(BeNil())
+	})
+	It("should accept cert SANs which are DNS names or wildcards", func() {
+		controlPlane.Spec.Master.CertSANs = []string{"api.example.com", "*.clusters.example.com"}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should reject cert SANs which aren't DNS names", func() {
+		controlPlane.Spec.Master.CertSANs = []string{"api.example.com", "https://api.example.com"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.master.certSANs[1]"))
+	})
+	It("should accept pod and service CIDRs which don't overlap", func() {
+		controlPlane.Spec.Network = &v1alpha1.Network{PodCIDR: "192.168.0.0/16", ServiceCIDR: "172.20.0.0/16"}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should reject pod and service CIDRs which overlap", func() {
+		controlPlane.Spec.Network = &v1alpha1.Network{PodCIDR: "10.0.0.0/8", ServiceCIDR: "10.96.0.0/12"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("overlaps"))
+	})
+	It("should accept the default network", func() {
+		controlPlane.Spec.Network = nil
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+		controlPlane.Spec.Network = &v1alpha1.Network{}
+		Expect(controlPlane.Validate(context.Background())).To(BeNil())
+	})
+	It("should reject pod CIDRs which overlap the default service CIDR", func() {
+		controlPlane.Spec.Network = &v1alpha1.Network{PodCIDR: "10.96.0.0/16"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring(v1alpha1.DefaultServiceCIDR))
+	})
+	It("should reject CIDRs with host bits set", func() {
+		controlPlane.Spec.Network = &v1alpha1.Network{ServiceCIDR: "10.96.0.1/12"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.network.serviceCIDR"))
+	})
+	It("should reject service CIDRs larger than the apiserver allows", func() {
+		controlPlane.Spec.Network = &v1alpha1.Network{ServiceCIDR: "10.0.0.0/8"}
+		Expect(controlPlane.Validate(context.Background()).Error()).To(ContainSubstring("spec.network.serviceCIDR"))
+	})
+	It("should reject changing the service CIDR of a cluster", func() {
+		original := controlPlane.DeepCopy()
+		controlPlane.Spec.Network = &v1alpha1.Network{ServiceCIDR: "172.20.0.0/16"}
+		ctx := apis.WithinUpdate(context.Background(), original)
+		Expect(controlPlane.Validate(ctx).Error()).To(ContainSubstring("spec.network.serviceCIDR"))
+		Expect(original.Validate(apis.WithinUpdate(context.Background(), original))).To(BeNil())
+	})
+	It("should reject changing the etcd storage of a cluster", func() {
+		original := controlPlane.DeepCopy()
+		size := resource.MustParse("40Gi")
+		controlPlane.Spec.Etcd.Storage = &v1alpha1.ETCDStorage{Size: &size}
+		Expect(controlPlane.Validate(apis.WithinUpdate(context.Background(), original)).Error()).To(ContainSubstring("spec.etcd.storage"))
+		Expect(controlPlane.Validate(apis.WithinCreate(context.Background()))).To(BeNil())
+	})
+	It("should reject deleting a ControlPlane protected by its template", func() {
+		template := &v1alpha1.ClusterTemplate{ObjectMeta: metav1.ObjectMeta{Name: "protected"}}
+		Expect(controlPlane.ValidateDelete(template, false)).To(Succeed())
+		template.Spec.ControlPlane.DeletionProtection = true
+		Expect(controlPlane.ValidateDelete(template, false).Error()).To(ContainSubstring("cluster template protected"))
+	})
+	It("should reject names which aren't DNS labels", func() {
+		controlPlane.Name = "1test.cluster"
+		Expect(controlPlane.Validate(apis.WithinCreate(context.Background())).Error()).To(ContainSubstring("metadata.name"))
+	})
+})
+
+var _ = Describe("ClusterSet Validation", func() {
+	var clusterSet *v1alpha1.ClusterSet
+	BeforeEach(func() {
+		clusterSet = &v1alpha1.ClusterSet{
+			ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", v1alpha1.MaxNameLength-2), Namespace: "default"},
+			Spec:       v1alpha1.ClusterSetSpec{Replicas: 10},
+		}
+	})
+	It("should accept names which leave room for the index of every member", func() {
+		Expect(clusterSet.Validate(apis.WithinCreate(context.Background()))).To(BeNil())
+	})
+	It("should reject names too long for the index of the last member", func() {
+		clusterSet.Name += "a"
+		Expect(clusterSet.Validate(apis.WithinCreate(context.Background())).Error()).To(ContainSubstring("metadata.name"))
+	})
+	It("should reject adding replicas whose names are too long", func() {
+		original := clusterSet.DeepCopy()
+		clusterSet.Spec.Replicas = 11
+		Expect(clusterSet.Validate(apis.WithinUpdate(context.Background(), original)).Error()).To(ContainSubstring("spec.replicas"))
+	})
+	It("should reject names which aren't DNS labels", func() {
+		clusterSet.Name = "1test.set"
+		Expect(clusterSet.Validate(apis.WithinCreate(context.Background())).Error()).To(ContainSubstring("metadata.name"))
+	})
+})
+
+var _ = Describe("AWSClusterName", func() {
+	It("should differ for clusters with the same name in different namespaces", func() {
+		teamA := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "team-a"}}
+		teamB := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "team-b"}}
+		Expect(teamA.QualifiedClusterName()).To(HavePrefix("test-"))
+		Expect(teamA.AWSClusterName()).ToNot(Equal(teamB.AWSClusterName()))
+		Expect(teamA.AWSClusterName()).To(Equal(teamA.DeepCopy().AWSClusterName()))
+	})
+	It("should truncate names too long for a label value", func() {
+		long := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 100), Namespace: "default"}}
+		Expect(long.AWSClusterName()).To(HaveLen(63))
+		Expect(long.AWSClusterName()).To(Equal(long.DeepCopy().AWSClusterName()))
+	})
+	It("should keep the name recorded in the status", func() {
+		legacy := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		legacy.Status.AWSClusterName = "test"
+		Expect(legacy.AWSClusterName()).To(Equal("test"))
+		Expect(legacy.HasLegacyClusterName()).To(BeTrue())
+	})
+})
@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+this file except in compliance with the License. You may obtain a copy of the
+License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software distributed
+under the License is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR
+CONDITIONS OF ANY KIND, either express or implied. See the License for the
+specific language governing permissions and limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+// ControlPlaneStatus defines the observed state of the ControlPlane of a cluster
+type ControlPlaneStatus struct {
+	// Conditions is the set of conditions required for this ControlPlane to create
+	// its objects, and indicates whether or not those conditions are met.
+	// +optional
+	Conditions apis.Conditions `json:"conditions,omitempty"`
+	// EstimatedHourlyCost is the approximate on-demand cost in USD per hour of
+	// the instances and load balancer provisioned for this ControlPlane. It's
+	// omitted when the price of an instance type isn't known.
+	// +optional
+	EstimatedHourlyCost string `json:"estimatedHourlyCost,omitempty"`
+	// Endpoint is the URL of the cluster's apiserver load balancer.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// Artifacts is the S3 URL the cluster's artifacts are uploaded under.
+	// +optional
+	Artifacts string `json:"artifacts,omitempty"`
+	// The fields below implement the Cluster API control plane provider
+	// contract, letting a CAPI Cluster use a ControlPlane as its control plane.
+	// Ready is true when all the master components have been reconciled.
+	// +optional
+	Ready bool `json:"ready,omitempty"`
+	// Initialized is true once the master components have been reconciled at
+	// least once, and stays true afterwards.
+	// +optional
+	Initialized bool `json:"initialized,omitempty"`
+	// ExternalManagedControlPlane is always true as the master components run
+	// as pods and not on CAPI Machines.
+	// +optional
+	ExternalManagedControlPlane bool `json:"externalManagedControlPlane,omitempty"`
+	// Version is the Kubernetes version the control plane was reconciled with.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// AWSClusterName is the name the cluster is known by in the tags of its
+	// AWS resources and the labels of its nodes, recorded when it's first
+	// reconciled. Clusters provisioned before the name was qualified with
+	// their namespace keep their bare name.
+	// +optional
+	AWSClusterName string `json:"awsClusterName,omitempty"`
+	// Etcd is the health of the etcd cluster as last reported by its members.
+	// +optional
+	Etcd *ETCDStatus `json:"etcd,omitempty"`
+	// Provisioning is how long each part of the cluster took to become ready
+	// after the ControlPlane was created, keyed by part, e.g. Etcd or
+	// APIServer. A part is added the first time it's ready and kept after.
+	// +optional
+	Provisioning map[string]metav1.Duration `json:"provisioning,omitempty"`
+	// Manifests are the rendered manifests of the components, keyed by
+	// component, e.g. apiserver, for auditing what a spec edit changed in the
+	// running control plane.
+	// +optional
+	Manifests map[string]ManifestStatus `json:"manifests,omitempty"`
+	// Guest is what the last passing probe of the guest apiserver found.
+	// +optional
+	Guest *GuestStatus `json:"guest,omitempty"`
+	// Availability is how long the guest apiserver was up and down since the
+	// cluster was provisioned, as measured by the probes.
+	// +optional
+	Availability *AvailabilityStatus `json:"availability,omitempty"`
+	// History has the last actions of the operator on the cluster, oldest
+	// first, e.g. the apiserver was created or its args changed.
+	// +optional
+	History []HistoryEntry `json:"history,omitempty"`
+}
+
+// HistoryEntry is an action of the operator on the cluster
+type HistoryEntry struct {
+	Time metav1.Time `json:"time"`
+	// Reason is the kind of action, e.g. ComponentCreated or Upgraded.
+	Reason string `json:"reason"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// AvailabilityStatus sums the time between probes of the guest apiserver by
+// the result of the later probe, for comparing the availability of builds.
+// The time is only as precise as the interval between probes.
+type AvailabilityStatus struct {
+	// Since is when the time started being counted.
+	Since metav1.Time `json:"since"`
+	// LastProbeTime is when the counted time was last written, when the
+	// probes started passing or failing and at least every 10 minutes. It's
+	// unset while the control plane is paused.
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	UpSeconds     int64        `json:"upSeconds"`
+	DownSeconds   int64        `json:"downSeconds"`
+	// Uptime is the percentage of the counted time the apiserver was up,
+	// e.g. 99.950%.
+	// +optional
+	Uptime string `json:"uptime,omitempty"`
+	// Incidents are the last windows the apiserver was down, oldest first.
+	// +optional
+	Incidents []Incident `json:"incidents,omitempty"`
+}
+
+// Incident is a window of failed probes of the guest apiserver
+type Incident struct {
+	// Start is when the probe before the first failed one ran, the time
+	// between them is counted as down.
+	Start metav1.Time `json:"start"`
+	// End is unset while the incident is ongoing.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+	// Check is the check which failed the first probe of the incident.
+	// +optional
+	Check string `json:"check,omitempty"`
+	// Message is the error of the first failed probe.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GuestStatus has the nodes registered with the guest cluster
+type GuestStatus struct {
+	Nodes      int32 `json:"nodes"`
+	ReadyNodes int32 `json:"readyNodes"`
+}
+
+// ManifestStatus has the hash of the images, commands, args and env of the
+// containers of a component and a summary of their last change
+type ManifestStatus struct {
+	Hash string `json:"hash"`
+	// LastChanged is when the containers were last changed by a reconcile.
+	// +optional
+	LastChanged *metav1.Time `json:"lastChanged,omitempty"`
+	// LastChange summarizes the change, e.g. kube-apiserver args added --v=4.
+	// +optional
+	LastChange string `json:"lastChange,omitempty"`
+}
+
+// ETCDStatus is the state of the etcd cluster, for finding quorum problems
+// without exec'ing into the etcd pods
+type ETCDStatus struct {
+	// Healthy is true when the cluster has a leader and no alarms are raised.
+	Healthy bool `json:"healthy"`
+	// +optional
+	Members []ETCDMember `json:"members,omitempty"`
+	// Leader is the name of the member which is the leader.
+	// +optional
+	Leader string `json:"leader,omitempty"`
+	// DBSizeBytes is the size of the database of the member which answered.
+	// +optional
+	DBSizeBytes int64 `json:"dbSizeBytes,omitempty"`
+	// Alarms raised on the members, e.g. NOSPACE when the quota is exceeded.
+	// +optional
+	Alarms []string `json:"alarms,omitempty"`
+	// Message explains why the health couldn't be checked.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when any of the fields above last changed.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ETCDMember is a member of the etcd cluster
+type ETCDMember struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	ClientURLs []string `json:"clientURLs,omitempty"`
+}
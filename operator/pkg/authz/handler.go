@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authz
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Handler serves the requests for a ControlPlane, of the form
+// <path><namespace>/<name>, only to the users allowed to get a subresource of
+// the ControlPlane. The bearer token of the request is authenticated with a
+// TokenReview and the user authorized with a SubjectAccessReview, so access is
+// granted with RBAC, e.g. a Role allowing get on controlplanes/logs.
+type Handler struct {
+	clientSet   kubernetes.Interface
+	path        string
+	subresource string
+	next        http.Handler
+}
+
+func NewHandler(clientSet kubernetes.Interface, path string, subresource string, next http.Handler) *Handler {
+	return &Handler{clientSet: clientSet, path: path, subresource: subresource, next: next}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		http.Error(w, "expected a bearer token", http.StatusUnauthorized)
+		return
+	}
+	review, err := h.clientSet.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reviewing token, %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !review.Status.Authenticated {
+		http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+	// Requests which aren't for a ControlPlane are authorized against every
+	// namespace, the next handler rejects them
+	var namespace, name string
+	if parts := strings.Split(strings.TrimPrefix(r.URL.Path, h.path), "/"); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	}
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range review.Status.User.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	access, err := h.clientSet.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   review.Status.User.Username,
+			UID:    review.Status.User.UID,
+			Groups: review.Status.User.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "get",
+				Group:       "kit.k8s.sh",
+				Resource:    "controlplanes",
+				Subresource: h.subresource,
+				Name:        name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reviewing access, %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !access.Status.Allowed {
+		http.Error(w, fmt.Sprintf("%s can't get controlplanes/%s of %s/%s", review.Status.User.Username, h.subresource, namespace, name), http.StatusForbidden)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,206 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bugreport
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/graph"
+	"github.com/awslabs/kit/operator/pkg/logs"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Path the handler is served on, requests are of the form
+// /bugreport/<namespace>/<name>
+const Path = "/bugreport/"
+
+// tailLines of the component and operator logs added to a report
+const tailLines = 2000
+
+// Handler serves a gzipped tarball with what's needed to debug a cluster: the
+// ControlPlane with its conditions, the dependency graph, the events of its
+// objects and the recent logs of its components and of the operator.
+type Handler struct {
+	kubeClient client.Client
+	clientSet  kubernetes.Interface
+	// operator is the pod of this replica, whose log lines for the cluster are
+	// added to the report
+	operator types.NamespacedName
+}
+
+func NewHandler(kubeClient client.Client, clientSet kubernetes.Interface, operator types.NamespacedName) *Handler {
+	return &Handler{kubeClient: kubeClient, clientSet: clientSet, operator: operator}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, fmt.Sprintf("expected %s<namespace>/<name>", Path), http.StatusBadRequest)
+		return
+	}
+	controlPlane := &v1alpha1.ControlPlane{}
+	if err := h.kubeClient.Get(r.Context(), object.NamespacedName(parts[1], parts[0]), controlPlane); err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	files, err := h.filesFor(r.Context(), controlPlane)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		fmt.Sprintf("%s-%s-%s.tar.gz", controlPlane.Namespace, controlPlane.Name, time.Now().UTC().Format("20060102T150405Z"))))
+	// The response has started, a failure can only truncate the tarball
+	_ = write(w, controlPlane.Name, files)
+}
+
+// filesFor collects the files of the report, a part which can't be collected
+// is replaced by the error so the rest of the report is still useful.
+func (h *Handler) filesFor(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	manifest, err := yaml.Marshal(controlPlane)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling control plane, %w", err)
+	}
+	files["controlplane.yaml"] = manifest
+	if dependencies, err := graph.For(ctx, h.kubeClient, controlPlane); err != nil {
+		files["graph.txt"] = []byte(fmt.Sprintf("building dependency graph, %v\n", err))
+	} else {
+		files["graph.txt"] = []byte(dependencies.Text(time.Now()))
+	}
+	files["events.txt"] = h.events(ctx, controlPlane)
+	components := &bytes.Buffer{}
+	if err := logs.NewHandler(h.clientSet).Stream(ctx, components, controlPlane.Namespace, controlPlane.Name, nil, &v1.PodLogOptions{
+		TailLines: aws.Int64(tailLines),
+	}); err != nil {
+		fmt.Fprintf(components, "getting component logs, %v\n", err)
+	}
+	files["logs/components.log"] = components.Bytes()
+	files["logs/operator.log"] = h.operatorLogs(ctx, controlPlane)
+	return files, nil
+}
+
+// events lists the events of the ControlPlane and of the objects generated for
+// it, which are all prefixed with the cluster name
+func (h *Handler) events(ctx context.Context, controlPlane *v1alpha1.ControlPlane) []byte {
+	events, err := h.clientSet.CoreV1().Events(controlPlane.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return []byte(fmt.Sprintf("listing events, %v\n", err))
+	}
+	var matching []v1.Event
+	for _, event := range events.Items {
+		if name := event.InvolvedObject.Name; name == controlPlane.Name || strings.HasPrefix(name, controlPlane.Name+"-") {
+			matching = append(matching, event)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].LastTimestamp.Before(&matching[j].LastTimestamp)
+	})
+	buffer := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	for _, event := range matching {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s/%s\t%d\t%s\n", event.LastTimestamp.UTC().Format(time.RFC3339), event.Type, event.Reason,
+			strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name, event.Count, event.Message)
+	}
+	_ = writer.Flush()
+	return buffer.Bytes()
+}
+
+// operatorLogs returns the recent lines logged by this replica while
+// reconciling the cluster, the structured logger adds its name and namespace
+// to every line of a reconcile.
+func (h *Handler) operatorLogs(ctx context.Context, controlPlane *v1alpha1.ControlPlane) []byte {
+	if h.operator.Name == "" {
+		return []byte("operator pod unknown\n")
+	}
+	stream, err := h.clientSet.CoreV1().Pods(h.operator.Namespace).GetLogs(h.operator.Name, &v1.PodLogOptions{
+		TailLines: aws.Int64(tailLines),
+	}).Stream(ctx)
+	if err != nil {
+		return []byte(fmt.Sprintf("getting operator logs, %v\n", err))
+	}
+	defer stream.Close()
+	namespace := fieldPattern("namespace", controlPlane.Namespace)
+	name := fieldPattern("name", controlPlane.Name)
+	buffer := &bytes.Buffer{}
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); namespace.Match(line) && name.Match(line) {
+			buffer.Write(line)
+			buffer.WriteByte('\n')
+		}
+	}
+	return buffer.Bytes()
+}
+
+// fieldPattern matches a field of both the console and json log encodings
+func fieldPattern(key, value string) *regexp.Regexp {
+	return regexp.MustCompile(fmt.Sprintf(`"%s":\s?"%s"`, regexp.QuoteMeta(key), regexp.QuoteMeta(value)))
+}
+
+// write writes the files to a gzipped tarball, in a directory named dir
+func write(w io.Writer, dir string, files map[string][]byte) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:    dir + "/" + name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("writing header of %s, %w", name, err)
+		}
+		if _, err := tarWriter.Write(files[name]); err != nil {
+			return fmt.Errorf("writing %s, %w", name, err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("closing tarball, %w", err)
+	}
+	return gzipWriter.Close()
+}
@@ -0,0 +1,199 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/kubeprovider"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	kubectlImage   = "bitnami/kubectl:1.20"
+	kubeConfigPath = "/etc/kubernetes/config"
+	filesPath      = "/etc/kit/addon"
+)
+
+// addon is installed in the guest cluster by a Job running next to the
+// control plane. The Job runs script in image with KUBECONFIG set to the
+// cluster's admin kubeconfig, and files mounted in its working directory.
+type addon struct {
+	name    string
+	enabled func(*v1alpha1.ControlPlane) bool
+	image   string
+	script  string
+	files   func(*v1alpha1.ControlPlane) map[string]string
+}
+
+type Controller struct {
+	kubeClient *kubeprovider.Client
+	addons     []addon
+}
+
+func New(kubeClient *kubeprovider.Client) *Controller {
+	return &Controller{kubeClient: kubeClient, addons: []addon{
+		ebsCSIDriver,
+		monitoring,
+		nvidiaDevicePlugin,
+		konnectivityAgent,
+		cloudControllerManagerRBAC,
+		csrApprover,
+		kubeProxy,
+	}}
+}
+
+// Reconcile starts a Job for every addon enabled on the control plane. Jobs
+// run again when what the addon installs changes, disabling an addon leaves it
+// installed in the guest cluster.
+func (c *Controller) Reconcile(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	for _, addon := range c.addons {
+		if !addon.enabled(controlPlane) {
+			continue
+		}
+		files := filesFor(controlPlane, addon)
+		if err := c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, files)); err != nil {
+			return fmt.Errorf("ensuring files for addon %s, %w", addon.name, err)
+		}
+		if err := c.ensureJob(ctx, object.WithOwner(controlPlane, jobFor(controlPlane, addon, checksumOf(addon, files)))); err != nil {
+			return fmt.Errorf("ensuring job for addon %s, %w", addon.name, err)
+		}
+	}
+	logging.FromContext(ctx).Debug("addons reconciled")
+	return nil
+}
+
+// ensureJob creates the Job, replacing a Job with another checksum. The pod
+// template of a Job is immutable, it can't be updated in place.
+func (c *Controller) ensureJob(ctx context.Context, job client.Object) error {
+	existing := &batchv1.Job{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKeyFromObject(job), existing); err != nil {
+		if errors.IsNotFound(err) {
+			return c.kubeClient.Create(ctx, job)
+		}
+		return fmt.Errorf("getting job, %w", err)
+	}
+	if existing.Annotations[object.ChecksumAnnotationKey] == job.GetAnnotations()[object.ChecksumAnnotationKey] {
+		return nil
+	}
+	if err := c.kubeClient.Delete(ctx, existing, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting job of a previous configuration, %w", err)
+	}
+	if err := c.kubeClient.Create(ctx, job); err != nil {
+		if kubeerrors.IsAlreadyExists(err) {
+			return fmt.Errorf("replacing job, %w", errors.WaitingForSubResources)
+		}
+		return err
+	}
+	return nil
+}
+
+// checksumOf hashes what an addon installs, the files are encoded with their
+// keys sorted
+func checksumOf(addon addon, files *v1.ConfigMap) string {
+	encoded, _ := json.Marshal(files.Data)
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(addon.image + addon.script))
+	_, _ = hash.Write(encoded)
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+func filesFor(controlPlane *v1alpha1.ControlPlane, addon addon) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      JobNameFor(controlPlane.ClusterName(), addon.name),
+			Namespace: controlPlane.Namespace,
+		},
+		Data: mirrorFiles(controlPlane.Spec.ImageRegistry, addon.files(controlPlane)),
+	}
+}
+
+func jobFor(controlPlane *v1alpha1.ControlPlane, addon addon, checksum string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        JobNameFor(controlPlane.ClusterName(), addon.name),
+			Namespace:   controlPlane.Namespace,
+			Annotations: map[string]string{object.ChecksumAnnotationKey: checksum},
+		},
+		Spec: batchv1.JobSpec{
+			// The guest apiserver might not be reachable through the load
+			// balancer yet when the job first runs
+			BackoffLimit: aws.Int32(10),
+			Template: v1.PodTemplateSpec{
+				Spec: master.WithProxy(controlPlane, v1.PodSpec{
+					RestartPolicy:    v1.RestartPolicyNever,
+					ImagePullSecrets: controlPlane.Spec.ImagePullSecrets,
+					Containers: []v1.Container{{
+						Name:       "install",
+						Image:      images.Mirror(controlPlane.Spec.ImageRegistry, addon.image),
+						WorkingDir: filesPath,
+						Command:    []string{"sh", "-c", addon.script},
+						Env: []v1.EnvVar{{
+							Name:  "KUBECONFIG",
+							Value: fmt.Sprintf("%s/%s", kubeConfigPath, secrets.SecretConfigKey),
+						}},
+						VolumeMounts: []v1.VolumeMount{
+							{Name: "kubeconfig", MountPath: kubeConfigPath, ReadOnly: true},
+							{Name: "files", MountPath: filesPath, ReadOnly: true},
+						},
+					}},
+					Volumes: []v1.Volume{{
+						Name: "kubeconfig",
+						VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+							SecretName: master.KubeAdminSecretNameFor(controlPlane.ClusterName()),
+						}},
+					}, {
+						Name: "files",
+						VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: JobNameFor(controlPlane.ClusterName(), addon.name)},
+						}},
+					}},
+				}),
+			},
+		},
+	}
+}
+
+// mirrorFiles pulls the images of the manifests applied to the guest cluster
+// from registry
+func mirrorFiles(registry string, files map[string]string) map[string]string {
+	for name, content := range files {
+		files[name] = images.MirrorManifest(registry, content)
+	}
+	return files
+}
+
+func enabled(addon *v1alpha1.Addon) bool {
+	return addon != nil && addon.Enabled
+}
+
+func JobNameFor(clusterName, addonName string) string {
+	return fmt.Sprintf("%s-addon-%s", clusterName, addonName)
+}
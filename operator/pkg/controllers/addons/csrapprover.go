@@ -0,0 +1,134 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// csrApprover lets KCM approve the client certificate requests of bootstrapping
+// and renewing kubelets, and runs kubelet-csr-approver for the serving
+// certificate requests, which KCM never approves. Serving certificates are only
+// approved for EC2 hostnames, ip-10-0-0-1.ec2.internal in us-east-1 and
+// ip-10-0-0-1.us-west-2.compute.internal in the other regions.
+var csrApprover = addon{
+	name: "csr-approver",
+	enabled: func(controlPlane *v1alpha1.ControlPlane) bool {
+		return enabled(controlPlane.Spec.Addons.CSRApprover)
+	},
+	image:  kubectlImage,
+	script: "kubectl apply -f node-client.yaml -f kubelet-csr-approver.yaml",
+	files: func(_ *v1alpha1.ControlPlane) map[string]string {
+		return map[string]string{
+			"node-client.yaml":          nodeClientCSRBindings,
+			"kubelet-csr-approver.yaml": kubeletCSRApprover,
+		}
+	},
+}
+
+const nodeClientCSRBindings = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kit:auto-approve-node-client-csrs
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:certificates.k8s.io:certificatesigningrequests:nodeclient
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: system:bootstrappers
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kit:auto-approve-renewed-node-client-csrs
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:certificates.k8s.io:certificatesigningrequests:selfnodeclient
+subjects:
+- apiGroup: rbac.authorization.k8s.io
+  kind: Group
+  name: system:nodes
+`
+
+const kubeletCSRApprover = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kubelet-csr-approver
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: kubelet-csr-approver
+rules:
+- apiGroups: ["certificates.k8s.io"]
+  resources: ["certificatesigningrequests"]
+  verbs: ["get", "list", "watch"]
+- apiGroups: ["certificates.k8s.io"]
+  resources: ["certificatesigningrequests/approval"]
+  verbs: ["update"]
+- apiGroups: ["certificates.k8s.io"]
+  resources: ["signers"]
+  resourceNames: ["kubernetes.io/kubelet-serving"]
+  verbs: ["approve"]
+- apiGroups: [""]
+  resources: ["events"]
+  verbs: ["create"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kubelet-csr-approver
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: kubelet-csr-approver
+subjects:
+- kind: ServiceAccount
+  name: kubelet-csr-approver
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kubelet-csr-approver
+  namespace: kube-system
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: kubelet-csr-approver
+  template:
+    metadata:
+      labels:
+        app: kubelet-csr-approver
+    spec:
+      serviceAccountName: kubelet-csr-approver
+      priorityClassName: system-cluster-critical
+      tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      containers:
+      - name: kubelet-csr-approver
+        image: ghcr.io/postfinance/kubelet-csr-approver:v0.2.2
+        env:
+        - name: PROVIDER_REGEX
+          value: ^ip-[0-9-]+(\.[a-z0-9-]+)?\.(ec2|compute)\.internal$
+        - name: BYPASS_DNS_RESOLUTION
+          value: "true"
+`
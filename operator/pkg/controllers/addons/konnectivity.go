@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+)
+
+// konnectivityAgent runs the konnectivity agent on every node of a cluster
+// with konnectivity enabled. The agents dial the server through the control
+// plane load balancer, its hostname is taken from the admin kubeconfig.
+var konnectivityAgent = addon{
+	name:    "konnectivity-agent",
+	enabled: master.KonnectivityEnabled,
+	image:   kubectlImage,
+	script: `host=$(kubectl config view --minify -o jsonpath='{.clusters[0].cluster.server}' | sed -e 's|^https://||' -e 's|:.*$||')` +
+		` && sed "s|PROXY_SERVER_HOST|${host}|" konnectivity-agent.yaml | kubectl apply -f -`,
+	files: func(_ *v1alpha1.ControlPlane) map[string]string {
+		return map[string]string{"konnectivity-agent.yaml": konnectivityAgentManifest}
+	},
+}
+
+var konnectivityAgentManifest = fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: konnectivity-agent
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: konnectivity-agent
+  namespace: kube-system
+  labels:
+    k8s-app: konnectivity-agent
+spec:
+  selector:
+    matchLabels:
+      k8s-app: konnectivity-agent
+  template:
+    metadata:
+      labels:
+        k8s-app: konnectivity-agent
+    spec:
+      priorityClassName: system-cluster-critical
+      serviceAccountName: konnectivity-agent
+      tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      containers:
+      - name: konnectivity-agent
+        image: k8s.gcr.io/kas-network-proxy/proxy-agent:v0.0.16
+        command: ["/proxy-agent"]
+        args:
+        - --logtostderr=true
+        - --ca-cert=/var/run/secrets/kubernetes.io/serviceaccount/ca.crt
+        - --proxy-server-host=PROXY_SERVER_HOST
+        - --proxy-server-port=%d
+        - --admin-server-port=8133
+        - --health-server-port=8134
+        - --service-account-token-path=/var/run/secrets/tokens/konnectivity-agent-token
+        livenessProbe:
+          httpGet:
+            port: 8134
+            path: /healthz
+          initialDelaySeconds: 15
+          timeoutSeconds: 15
+        volumeMounts:
+        - mountPath: /var/run/secrets/tokens
+          name: konnectivity-agent-token
+      volumes:
+      - name: konnectivity-agent-token
+        projected:
+          sources:
+          - serviceAccountToken:
+              path: konnectivity-agent-token
+              audience: %s
+`, master.KonnectivityAgentPort, master.KonnectivityAudience)
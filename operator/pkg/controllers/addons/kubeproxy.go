@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/config"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// kubeProxy runs kube-proxy on every node. kube-proxy programs the service
+// addresses, so it can't reach the apiserver through the kubernetes service
+// and its kubeconfig points at the control plane load balancer, taken from the
+// admin kubeconfig. kube-proxy sets the conntrack sysctls and loads the IPVS
+// modules itself, the nodes don't need to be configured for either mode.
+var kubeProxy = addon{
+	name: "kube-proxy",
+	enabled: func(controlPlane *v1alpha1.ControlPlane) bool {
+		return controlPlane.Spec.Addons.KubeProxy != nil && controlPlane.Spec.Addons.KubeProxy.Enabled &&
+			controlPlane.Spec.Addons.KubeProxy.Mode != v1alpha1.KubeProxyModeNone
+	},
+	image: kubectlImage,
+	script: `server=$(kubectl config view --minify -o jsonpath='{.clusters[0].cluster.server}')` +
+		` && sed "s|APISERVER_URL|${server}|" kube-proxy.yaml | kubectl apply -f -`,
+	files: func(controlPlane *v1alpha1.ControlPlane) map[string]string {
+		mode := controlPlane.Spec.Addons.KubeProxy.Mode
+		if mode == "" {
+			mode = v1alpha1.KubeProxyModeIPTables
+		}
+		return map[string]string{"kube-proxy.yaml": fmt.Sprintf(kubeProxyManifest, mode, controlPlane.PodCIDR(), kubeProxyImageFor(controlPlane))}
+	},
+}
+
+// kubeProxyImageFor returns the kube-proxy image of the release the apiserver
+// runs, the images of a release only differ by the name of the component. The
+// image registry of the cluster is applied to the manifest like for every
+// addon.
+func kubeProxyImageFor(controlPlane *v1alpha1.ControlPlane) string {
+	image := config.DefaultAPIServerImage
+	if apiServer := controlPlane.Spec.Master.APIServer; apiServer != nil && strings.Contains(apiServer.Image, "kube-apiserver") {
+		image = apiServer.Image
+	}
+	return strings.Replace(image, "kube-apiserver", "kube-proxy", 1)
+}
+
+const kubeProxyManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kube-proxy
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kit:node-proxier
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:node-proxier
+subjects:
+- kind: ServiceAccount
+  name: kube-proxy
+  namespace: kube-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kube-proxy
+  namespace: kube-system
+data:
+  config.conf: |-
+    apiVersion: kubeproxy.config.k8s.io/v1alpha1
+    kind: KubeProxyConfiguration
+    clientConnection:
+      kubeconfig: /var/lib/kube-proxy/kubeconfig.conf
+    mode: %q
+    clusterCIDR: %q
+    conntrack:
+      maxPerCore: 32768
+      min: 131072
+  kubeconfig.conf: |-
+    apiVersion: v1
+    kind: Config
+    clusters:
+    - name: default
+      cluster:
+        certificate-authority: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt
+        server: APISERVER_URL
+    contexts:
+    - name: default
+      context:
+        cluster: default
+        namespace: default
+        user: default
+    current-context: default
+    users:
+    - name: default
+      user:
+        tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: kube-proxy
+  namespace: kube-system
+  labels:
+    k8s-app: kube-proxy
+spec:
+  selector:
+    matchLabels:
+      k8s-app: kube-proxy
+  updateStrategy:
+    type: RollingUpdate
+  template:
+    metadata:
+      labels:
+        k8s-app: kube-proxy
+    spec:
+      hostNetwork: true
+      priorityClassName: system-node-critical
+      serviceAccountName: kube-proxy
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: kube-proxy
+        image: %s
+        command:
+        - kube-proxy
+        - --config=/var/lib/kube-proxy/config.conf
+        - --hostname-override=$(NODE_NAME)
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - name: kube-proxy
+          mountPath: /var/lib/kube-proxy
+        - name: xtables-lock
+          mountPath: /run/xtables.lock
+        - name: lib-modules
+          mountPath: /lib/modules
+          readOnly: true
+      volumes:
+      - name: kube-proxy
+        configMap:
+          name: kube-proxy
+      - name: xtables-lock
+        hostPath:
+          path: /run/xtables.lock
+          type: FileOrCreate
+      - name: lib-modules
+        hostPath:
+          path: /lib/modules
+`
@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addons
+
+import (
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// nvidiaDevicePlugin installs the NVIDIA device plugin DaemonSet, which
+// advertises nvidia.com/gpu on nodes running the EKS GPU optimized AMI.
+var nvidiaDevicePlugin = addon{
+	name: "nvidia-device-plugin",
+	enabled: func(controlPlane *v1alpha1.ControlPlane) bool {
+		return enabled(controlPlane.Spec.Addons.NVIDIADevicePlugin)
+	},
+	image:  kubectlImage,
+	script: "kubectl apply -f nvidia-device-plugin.yml",
+	files: func(_ *v1alpha1.ControlPlane) map[string]string {
+		return map[string]string{"nvidia-device-plugin.yml": nvidiaDevicePluginManifest}
+	},
+}
+
+// nvidiaDevicePluginManifest is nvidia-device-plugin.yml of the v0.9.0 release
+// of k8s-device-plugin. It's vendored so that clusters don't fetch it from
+// GitHub.
+const nvidiaDevicePluginManifest = `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: nvidia-device-plugin-daemonset
+  namespace: kube-system
+spec:
+  selector:
+    matchLabels:
+      name: nvidia-device-plugin-ds
+  updateStrategy:
+    type: RollingUpdate
+  template:
+    metadata:
+      annotations:
+        scheduler.alpha.kubernetes.io/critical-pod: ""
+      labels:
+        name: nvidia-device-plugin-ds
+    spec:
+      tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      - key: nvidia.com/gpu
+        operator: Exists
+        effect: NoSchedule
+      priorityClassName: "system-node-critical"
+      containers:
+      - image: nvcr.io/nvidia/k8s-device-plugin:v0.9.0
+        name: nvidia-device-plugin-ctr
+        args: ["--fail-on-init-error=false"]
+        securityContext:
+          allowPrivilegeEscalation: false
+          capabilities:
+            drop: ["ALL"]
+        volumeMounts:
+        - name: device-plugin
+          mountPath: /var/lib/kubelet/device-plugins
+      volumes:
+      - name: device-plugin
+        hostPath:
+          path: /var/lib/kubelet/device-plugins
+`
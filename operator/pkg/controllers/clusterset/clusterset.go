@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/results"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type clusterSet struct {
+	kubeClient client.Client
+}
+
+// NewController returns a controller for managing sets of ControlPlanes
+func NewController(kubeClient client.Client) *clusterSet {
+	return &clusterSet{kubeClient: kubeClient}
+}
+
+// Name returns the name of the controller
+func (c *clusterSet) Name() string {
+	return "cluster-set"
+}
+
+// For returns the resource this controller is for.
+func (c *clusterSet) For() controllers.Object {
+	return &v1alpha1.ClusterSet{}
+}
+
+// Owns returns the members of a ClusterSet
+func (c *clusterSet) Owns() []client.Object {
+	return []client.Object{&v1alpha1.ControlPlane{}}
+}
+
+// Reconcile creates the missing member ControlPlanes, updates the existing
+// ones to match the template and deletes members above the desired replicas.
+// ClusterSet.Status reports how many of the members exist and are ready.
+func (c *clusterSet) Reconcile(ctx context.Context, object controllers.Object) (*reconcile.Result, error) {
+	clusterSet := object.(*v1alpha1.ClusterSet)
+	members, err := c.membersFor(ctx, clusterSet)
+	if err != nil {
+		return nil, err
+	}
+	clusterSet.Status.Replicas, clusterSet.Status.ReadyReplicas = 0, 0
+	for i := 0; i < int(clusterSet.Spec.Replicas); i++ {
+		name := clusterSet.MemberNameFor(i)
+		member, ok := members[name]
+		delete(members, name)
+		if !ok {
+			if err := c.create(ctx, clusterSet, name); err != nil {
+				return nil, err
+			}
+		} else if err := c.update(ctx, clusterSet, member); err != nil {
+			return nil, err
+		}
+		clusterSet.Status.Replicas++
+		if ok && member.StatusConditions().IsHappy() {
+			clusterSet.Status.ReadyReplicas++
+		}
+	}
+	// Anything left over is beyond the desired number of replicas. Members
+	// with deletion protection are kept until it's removed, the webhook would
+	// reject deleting them.
+	var protected []string
+	for _, member := range members {
+		template, err := c.templateFor(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		if err := member.ValidateDelete(template, false); err != nil {
+			protected = append(protected, err.Error())
+			clusterSet.Status.Replicas++
+			continue
+		}
+		if err := c.kubeClient.Delete(ctx, member); err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("deleting control plane %s, %w", member.Name, err)
+		}
+		logging.FromContext(ctx).Infof("Deleted control plane %s", member.Name)
+	}
+	if len(protected) > 0 {
+		sort.Strings(protected)
+		clusterSet.StatusConditions().MarkTrueWithReason(v1alpha1.ScaleDownBlocked, "DeletionProtection", strings.Join(protected, "; "))
+	} else {
+		_ = clusterSet.StatusConditions().ClearCondition(v1alpha1.ScaleDownBlocked)
+	}
+	return results.Created, nil
+}
+
+func (c *clusterSet) Finalize(_ context.Context, _ controllers.Object) (*reconcile.Result, error) {
+	return results.Terminated, nil
+}
+
+func (c *clusterSet) membersFor(ctx context.Context, clusterSet *v1alpha1.ClusterSet) (map[string]*v1alpha1.ControlPlane, error) {
+	controlPlanes := &v1alpha1.ControlPlaneList{}
+	if err := c.kubeClient.List(ctx, controlPlanes, client.InNamespace(clusterSet.Namespace),
+		client.MatchingLabels{object.ClusterSetLabelKey: clusterSet.Name}); err != nil {
+		return nil, fmt.Errorf("listing control planes, %w", err)
+	}
+	members := map[string]*v1alpha1.ControlPlane{}
+	for i := range controlPlanes.Items {
+		members[controlPlanes.Items[i].Name] = &controlPlanes.Items[i]
+	}
+	return members, nil
+}
+
+// templateFor returns the ClusterTemplate of the member, or nil when it has
+// none or it doesn't exist
+func (c *clusterSet) templateFor(ctx context.Context, member *v1alpha1.ControlPlane) (*v1alpha1.ClusterTemplate, error) {
+	if member.Spec.Template == "" {
+		return nil, nil
+	}
+	template := &v1alpha1.ClusterTemplate{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(member.Spec.Template, member.Namespace), template); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting cluster template %s, %w", member.Spec.Template, err)
+	}
+	return template, nil
+}
+
+func (c *clusterSet) create(ctx context.Context, clusterSet *v1alpha1.ClusterSet, name string) error {
+	member := object.WithOwner(clusterSet, &v1alpha1.ControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: clusterSet.Namespace,
+			Labels:    map[string]string{object.ClusterSetLabelKey: clusterSet.Name},
+		},
+		Spec: specFor(ctx, clusterSet),
+	})
+	if err := c.kubeClient.Create(ctx, member); err != nil {
+		return fmt.Errorf("creating control plane %s, %w", name, err)
+	}
+	logging.FromContext(ctx).Infof("Created control plane %s", name)
+	return nil
+}
+
+func (c *clusterSet) update(ctx context.Context, clusterSet *v1alpha1.ClusterSet, member *v1alpha1.ControlPlane) error {
+	desired := specFor(ctx, clusterSet)
+	if equality.Semantic.DeepEqual(member.Spec, desired) {
+		return nil
+	}
+	persisted := member.DeepCopy()
+	member.Spec = desired
+	if err := c.kubeClient.Patch(ctx, member, client.MergeFrom(persisted)); err != nil {
+		return fmt.Errorf("patching control plane %s, %w", member.Name, err)
+	}
+	return nil
+}
+
+// specFor returns the template defaulted the same way the webhook defaults a
+// ControlPlane, so that members aren't patched back and forth on every loop.
+func specFor(ctx context.Context, clusterSet *v1alpha1.ClusterSet) v1alpha1.ControlPlaneSpec {
+	spec := clusterSet.Spec.Template.DeepCopy()
+	spec.SetDefaults(ctx)
+	return *spec
+}
+
+// MemberNameFor returns the name of the ControlPlane at index in the set
+func MemberNameFor(clusterSetName string, index int) string {
+	return (&v1alpha1.ClusterSet{ObjectMeta: metav1.ObjectMeta{Name: clusterSetName}}).MemberNameFor(index)
+}
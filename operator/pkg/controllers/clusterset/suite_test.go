@@ -0,0 +1,135 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterset_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/clusterset"
+	"github.com/awslabs/kit/operator/pkg/test/environment"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/awslabs/kit/operator/pkg/test/expectations"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	controller controllers.Controller
+	kubeClient client.Client
+	env        *environment.Environment
+	scheme     = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+}
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ClusterSet")
+}
+
+var _ = BeforeSuite(func() {
+	env = environment.New()
+	Expect(env.Start(scheme)).To(Succeed(), "Failed to start environment")
+	kubeClient = env.Client
+	controller = clusterset.NewController(kubeClient)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = Describe("ClusterSet", func() {
+	var clusterSet *v1alpha1.ClusterSet
+	BeforeEach(func() {
+		clusterSet = &v1alpha1.ClusterSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "testset", Namespace: "default"},
+			Spec: v1alpha1.ClusterSetSpec{
+				Replicas: 3,
+				Template: v1alpha1.ControlPlaneSpec{Master: v1alpha1.MasterSpec{Instances: v1alpha1.Instances{Type: "m5.large"}}},
+			},
+		}
+	})
+	AfterEach(func() {
+		ExpectCleanedUp(kubeClient)
+	})
+	Context("Reconcilation", func() {
+		It("should create a control plane for every replica", func() {
+			ExpectCreated(kubeClient, clusterSet)
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(clusterSet))
+			controlPlanes := ExpectMembers(clusterSet, 3)
+			for _, controlPlane := range controlPlanes {
+				Expect(controlPlane.Spec.Master.Type).To(Equal("m5.large"))
+			}
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(clusterSet), clusterSet)).To(Succeed())
+			Expect(clusterSet.Status.Replicas).To(BeEquivalentTo(3))
+			Expect(clusterSet.Status.ReadyReplicas).To(BeZero())
+		})
+		It("should delete control planes when scaled down", func() {
+			ExpectCreated(kubeClient, clusterSet)
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(clusterSet))
+			ExpectMembers(clusterSet, 3)
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(clusterSet), clusterSet)).To(Succeed())
+			persisted := clusterSet.DeepCopy()
+			clusterSet.Spec.Replicas = 1
+			Expect(kubeClient.Patch(context.Background(), clusterSet, client.MergeFrom(persisted))).To(Succeed())
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(clusterSet))
+			controlPlanes := ExpectMembers(clusterSet, 1)
+			Expect(controlPlanes[0].Name).To(Equal(clusterset.MemberNameFor(clusterSet.Name, 0)))
+		})
+		It("should keep members with deletion protection when scaled down", func() {
+			clusterSet.Spec.Template.DeletionProtection = true
+			ExpectCreated(kubeClient, clusterSet)
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(clusterSet))
+			ExpectMembers(clusterSet, 3)
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(clusterSet), clusterSet)).To(Succeed())
+			persisted := clusterSet.DeepCopy()
+			clusterSet.Spec.Replicas = 1
+			Expect(kubeClient.Patch(context.Background(), clusterSet, client.MergeFrom(persisted))).To(Succeed())
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(clusterSet))
+			ExpectMembers(clusterSet, 3)
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(clusterSet), clusterSet)).To(Succeed())
+			Expect(clusterSet.StatusConditions().GetCondition(v1alpha1.Active).IsTrue()).To(BeTrue())
+			blocked := clusterSet.StatusConditions().GetCondition(v1alpha1.ScaleDownBlocked)
+			Expect(blocked.IsTrue()).To(BeTrue())
+			Expect(blocked.Message).To(ContainSubstring(clusterset.MemberNameFor(clusterSet.Name, 2)))
+			Expect(clusterSet.Status.Replicas).To(BeEquivalentTo(3))
+		})
+	})
+})
+
+func genericController() *controllers.GenericController {
+	return &controllers.GenericController{Controller: controller, Client: kubeClient}
+}
+
+func ExpectMembers(clusterSet *v1alpha1.ClusterSet, count int) []v1alpha1.ControlPlane {
+	controlPlanes := &v1alpha1.ControlPlaneList{}
+	Eventually(func() []v1alpha1.ControlPlane {
+		Expect(kubeClient.List(context.Background(), controlPlanes, client.InNamespace(clusterSet.Namespace),
+			client.MatchingLabels{object.ClusterSetLabelKey: clusterSet.Name})).To(Succeed())
+		return controlPlanes.Items
+	}, ReconcilerPropagationTime, RequestInterval).Should(HaveLen(count))
+	return controlPlanes.Items
+}
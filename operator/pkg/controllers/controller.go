@@ -18,19 +18,35 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/errors"
 	"github.com/awslabs/kit/operator/pkg/results"
-	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var (
 	FinalizerForAWSResources = v1alpha1.SchemeGroupVersion.Group + "/%s"
+	// SyncPeriod is how often a resource is reconciled again once it has
+	// converged, to detect drift of the objects generated for it.
+	SyncPeriod = time.Minute
+	// SyncPeriods overrides SyncPeriod for the controllers with these names.
+	SyncPeriods = map[string]time.Duration{}
+	// Shard, when set, limits the resources reconciled by this replica to the
+	// ones of its shard.
+	Shard interface {
+		Owns(types.NamespacedName) bool
+	}
 )
 
 // GenericController implements controllerruntime.Reconciler and runs a
@@ -38,10 +54,21 @@ var (
 type GenericController struct {
 	Controller
 	client.Client
+	// Recorder, when set, records an event when a resource is stalled
+	Recorder record.EventRecorder
 }
 
 // Reconcile executes a control loop for the resource
 func (c *GenericController) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	// Every log line of this reconcile carries the resource and an ID to
+	// correlate the lines of a single reconcile
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(
+		"controller", c.Name(), "namespace", req.Namespace, "name", req.Name, "reconcileID", uuid.NewUUID()))
+	// Resources of other shards are checked again later, as they move to this
+	// replica when the members of the shards change.
+	if Shard != nil && !Shard.Owns(req.NamespacedName) {
+		return reconcile.Result{RequeueAfter: c.syncPeriod()}, nil
+	}
 	// 1. Read Spec
 	resource := c.For()
 	if err := c.Get(ctx, req.NamespacedName, resource); err != nil {
@@ -56,12 +83,19 @@ func (c *GenericController) Reconcile(ctx context.Context, req reconcile.Request
 		once this bug is fixed https://github.com/kubernetes/client-go/issues/1004
 	*/
 	if resource.GetObjectKind().GroupVersionKind().Empty() {
-		resource.GetObjectKind().SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind(v1alpha1.ControlPlaneKind))
+		gvk, err := apiutil.GVKForObject(resource, c.Scheme())
+		if err != nil {
+			return *results.Failed, fmt.Errorf("getting group version kind for %s, %w", req.NamespacedName, err)
+		}
+		resource.GetObjectKind().SetGroupVersionKind(gvk)
 	}
 	// 2. Copy object for merge patch base
 	persisted := resource.DeepCopyObject()
 	// 3. Reconcile else finalize if object is deleted
 	result, reconcileErr := c.reconcile(ctx, resource, persisted)
+	if resource.GetDeletionTimestamp() == nil {
+		c.detectStall(ctx, resource, reconcileErr)
+	}
 	// 4. Update Status using a merge patch, we want to set status even when reconcile errored
 	if err := c.Status().Patch(ctx, resource, client.MergeFrom(persisted)); err != nil && !errors.IsNotFound(err) {
 		return *results.Failed, fmt.Errorf("status patch for %s, %w,", req.NamespacedName, err)
@@ -89,6 +123,9 @@ func (c *GenericController) reconcile(ctx context.Context, resource Object, pers
 			resource.StatusConditions().MarkFalse(v1alpha1.Active, "", err.Error())
 			return *results.Failed, fmt.Errorf("reconciling resource, %w", err)
 		}
+		if result == results.Created {
+			result = &reconcile.Result{RequeueAfter: c.syncPeriod()}
+		}
 		resource.StatusConditions().MarkTrue(v1alpha1.Active)
 	} else {
 		if result, err = c.Controller.Finalize(ctx, resource); err != nil {
@@ -96,7 +133,7 @@ func (c *GenericController) reconcile(ctx context.Context, resource Object, pers
 		}
 		// Remove finalizer for this controller
 		resource.SetFinalizers(existingFinalizerSet.Difference(finalizerStr).UnsortedList())
-		zap.S().Infof("[%s] Successfully deleted", resource.GetName())
+		logging.FromContext(ctx).Info("Successfully deleted")
 	}
 	// If the finalizers have changed merge patch the object
 	if !reflect.DeepEqual(existingFinalizers, resource.GetFinalizers()) {
@@ -106,3 +143,37 @@ func (c *GenericController) reconcile(ctx context.Context, resource Object, pers
 	}
 	return *result, nil
 }
+
+func (c *GenericController) syncPeriod() time.Duration {
+	return SyncPeriodFor(c.Name())
+}
+
+// SyncPeriodFor returns how often the converged resources of a controller are
+// reconciled
+func SyncPeriodFor(name string) time.Duration {
+	if period, ok := SyncPeriods[name]; ok {
+		return period
+	}
+	return SyncPeriod
+}
+
+// ParseSyncPeriods parses comma separated controller=duration pairs, e.g.
+// control-plane=5m,load-test=30s
+func ParseSyncPeriods(periods string) (map[string]time.Duration, error) {
+	parsed := map[string]time.Duration{}
+	for _, period := range strings.Split(periods, ",") {
+		if period = strings.TrimSpace(period); period == "" {
+			continue
+		}
+		parts := strings.SplitN(period, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid sync period %q, expected controller=duration", period)
+		}
+		duration, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid sync period %q, %w", period, err)
+		}
+		parsed[parts[0]] = duration
+	}
+	return parsed, nil
+}
@@ -0,0 +1,242 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/kubeprovider"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// auditedComponents are the workloads whose containers are audited, keyed by
+// the component names of the logs endpoint
+var auditedComponents = map[string]func(clusterName string) string{
+	"etcd":                     etcd.ServiceNameFor,
+	"apiserver":                master.APIServerDeploymentName,
+	"controller-manager":       master.KCMDeploymentName,
+	"scheduler":                master.SchedulerDeploymentName,
+	"cloud-controller-manager": master.CCMDeploymentName,
+}
+
+// containerManifest is the part of a container a spec edit changes
+type containerManifest struct {
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	Command []string `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	Env     []string `json:"env,omitempty"`
+}
+
+// auditManifests compares the containers of each component to the ones seen by
+// the last reconcile, kept in a ConfigMap as the status only has their hash.
+// A change is summarized in the status and recorded as an event, components
+// which appeared or disappeared are added to the history. The workloads
+// are read from the cache, a change applied by this reconcile can be seen by
+// the next one.
+func (c *controlPlane) auditManifests(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	current, err := c.manifests(ctx, controlPlane)
+	if err != nil {
+		return err
+	}
+	seen := &v1.ConfigMap{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(ManifestsConfigMapNameFor(controlPlane.ClusterName()), controlPlane.Namespace), seen); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("getting manifests config map, %w", err)
+	}
+	statuses := map[string]v1alpha1.ManifestStatus{}
+	data := map[string]string{}
+	for _, component := range sets.StringKeySet(current).List() {
+		containers := current[component]
+		encoded, err := json.Marshal(containers)
+		if err != nil {
+			return fmt.Errorf("encoding containers of %s, %w", component, err)
+		}
+		data[component] = string(encoded)
+		status := controlPlane.Status.Manifests[component]
+		status.Hash = hashOf(encoded)
+		switch previous, ok := seen.Data[component]; {
+		case !ok:
+			remember(controlPlane, "ComponentCreated", component+" created")
+		case previous != data[component]:
+			var before []containerManifest
+			if err := json.Unmarshal([]byte(previous), &before); err != nil {
+				return fmt.Errorf("decoding containers of %s, %w", component, err)
+			}
+			now := metav1.Now()
+			status.LastChanged = &now
+			status.LastChange = diff(before, containers)
+			if c.options.Recorder != nil {
+				c.options.Recorder.Eventf(controlPlane, v1.EventTypeNormal, "ManifestChanged", "%s %s", component, status.LastChange)
+			}
+			remember(controlPlane, "ManifestChanged", component+" "+status.LastChange)
+		}
+		statuses[component] = status
+	}
+	for _, component := range sets.StringKeySet(seen.Data).List() {
+		if _, ok := data[component]; !ok {
+			remember(controlPlane, "ComponentDeleted", component+" deleted")
+		}
+	}
+	controlPlane.Status.Manifests = statuses
+	if reflect.DeepEqual(seen.Data, data) || len(seen.Data)+len(data) == 0 {
+		return nil
+	}
+	if err := kubeprovider.New(c.kubeClient).EnsureApply(ctx, object.WithOwner(controlPlane, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ManifestsConfigMapNameFor(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+		},
+		Data: data,
+	})); err != nil {
+		return fmt.Errorf("saving manifests, %w", err)
+	}
+	return nil
+}
+
+// manifests returns the containers of each component which exists
+func (c *controlPlane) manifests(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (map[string][]containerManifest, error) {
+	manifests := map[string][]containerManifest{}
+	for component, nameFor := range auditedComponents {
+		var spec *v1.PodSpec
+		var workload client.Object
+		if component == "etcd" {
+			statefulSet := &appsv1.StatefulSet{}
+			workload, spec = statefulSet, &statefulSet.Spec.Template.Spec
+		} else {
+			deployment := &appsv1.Deployment{}
+			workload, spec = deployment, &deployment.Spec.Template.Spec
+		}
+		if err := c.kubeClient.Get(ctx, object.NamespacedName(nameFor(controlPlane.ClusterName()), controlPlane.Namespace), workload); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("getting %s, %w", component, err)
+		}
+		for _, container := range append(spec.InitContainers, spec.Containers...) {
+			manifests[component] = append(manifests[component], containerManifest{
+				Name:    container.Name,
+				Image:   container.Image,
+				Command: container.Command,
+				Args:    container.Args,
+				Env:     envOf(container.Env),
+			})
+		}
+	}
+	return manifests, nil
+}
+
+// envOf returns the variables as NAME=value, credentials in proxy URLs are
+// redacted as the summaries end up in the status and events
+func envOf(env []v1.EnvVar) (variables []string) {
+	for _, variable := range env {
+		value := variable.Value
+		switch from := variable.ValueFrom; {
+		case from == nil:
+			if u, err := url.Parse(value); err == nil && u.User != nil {
+				u.User = url.User("redacted")
+				value = u.String()
+			}
+		case from.FieldRef != nil:
+			value = "<" + from.FieldRef.FieldPath + ">"
+		case from.SecretKeyRef != nil:
+			value = fmt.Sprintf("<secret %s/%s>", from.SecretKeyRef.Name, from.SecretKeyRef.Key)
+		case from.ConfigMapKeyRef != nil:
+			value = fmt.Sprintf("<configmap %s/%s>", from.ConfigMapKeyRef.Name, from.ConfigMapKeyRef.Key)
+		default:
+			value = "<reference>"
+		}
+		variables = append(variables, variable.Name+"="+value)
+	}
+	return variables
+}
+
+// diff summarizes the changes to the containers, e.g.
+// kube-apiserver args added --v=4, removed --v=2
+func diff(before, after []containerManifest) string {
+	previous := map[string]containerManifest{}
+	for _, container := range before {
+		previous[container.Name] = container
+	}
+	var changes []string
+	for _, container := range after {
+		old, ok := previous[container.Name]
+		delete(previous, container.Name)
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s added", container.Name))
+			continue
+		}
+		if old.Image != container.Image {
+			changes = append(changes, fmt.Sprintf("%s image %s -> %s", container.Name, old.Image, container.Image))
+		}
+		if !reflect.DeepEqual(old.Command, container.Command) {
+			changes = append(changes, fmt.Sprintf("%s command %s -> %s", container.Name, strings.Join(old.Command, " "), strings.Join(container.Command, " ")))
+		}
+		if change := diffList(old.Args, container.Args); change != "" {
+			changes = append(changes, fmt.Sprintf("%s args %s", container.Name, change))
+		}
+		if change := diffList(old.Env, container.Env); change != "" {
+			changes = append(changes, fmt.Sprintf("%s env %s", container.Name, change))
+		}
+	}
+	for _, container := range before {
+		if _, ok := previous[container.Name]; ok {
+			changes = append(changes, fmt.Sprintf("%s removed", container.Name))
+		}
+	}
+	if len(changes) == 0 {
+		return "containers reordered"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// diffList returns the items added to and removed from a list, or an empty
+// string when it only changed order
+func diffList(before, after []string) string {
+	added := sets.NewString(after...).Difference(sets.NewString(before...)).List()
+	removed := sets.NewString(before...).Difference(sets.NewString(after...)).List()
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, "added "+strings.Join(added, " "))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, "removed "+strings.Join(removed, " "))
+	}
+	return strings.Join(changes, ", ")
+}
+
+func hashOf(data []byte) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write(data)
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+func ManifestsConfigMapNameFor(clusterName string) string {
+	return fmt.Sprintf("%s-manifests", clusterName)
+}
@@ -0,0 +1,50 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// recordAWSClusterName records the name of the cluster in its status the
+// first time it's reconciled. Clusters whose etcd StatefulSet was created
+// before the name was recorded run on nodes labeled with their bare name and
+// keep it.
+func (c *controlPlane) recordAWSClusterName(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	if controlPlane.Status.AWSClusterName != "" {
+		return nil
+	}
+	if controlPlane.Status.Initialized {
+		controlPlane.Status.AWSClusterName = controlPlane.Name
+		return nil
+	}
+	err := c.kubeClient.Get(ctx, object.NamespacedName(etcd.ServiceNameFor(controlPlane.ClusterName()), controlPlane.Namespace), &appsv1.StatefulSet{})
+	switch {
+	case err == nil:
+		controlPlane.Status.AWSClusterName = controlPlane.Name
+	case errors.IsNotFound(err):
+		controlPlane.Status.AWSClusterName = controlPlane.QualifiedClusterName()
+	default:
+		return fmt.Errorf("getting etcd statefulset, %w", err)
+	}
+	return nil
+}
@@ -17,28 +17,94 @@ package controlplane
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/addons"
 	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
 	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/cost"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/guard"
+	"github.com/awslabs/kit/operator/pkg/health"
 	"github.com/awslabs/kit/operator/pkg/kubeprovider"
+	"github.com/awslabs/kit/operator/pkg/notifications"
+	"github.com/awslabs/kit/operator/pkg/quota"
+	"github.com/awslabs/kit/operator/pkg/references"
 	"github.com/awslabs/kit/operator/pkg/results"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/reconciler"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/logging"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// Options configure the control plane controller
+type Options struct {
+	// FederationRemoteWriteURL, when set, enables the monitoring addon on every
+	// ControlPlane which doesn't configure it, remote writing the key SLI
+	// metrics of all the clusters to this one endpoint.
+	FederationRemoteWriteURL string
+	// Publisher, when set, is sent an event whenever a cluster is provisioned,
+	// upgraded, fails or is deleted.
+	Publisher notifications.Publisher
+	// StuckDeletionTimeout is how long a ControlPlane can be deleting, waiting
+	// on finalizers of other controllers, before a DeletionStuck event is
+	// published, defaults to 10 minutes.
+	StuckDeletionTimeout time.Duration
+	// Quotas, when set, is checked before the first objects of a cluster are
+	// created, so a cluster the account can't run fails fast instead of
+	// being half provisioned.
+	Quotas quota.Checker
+	// References, when set, is checked until a cluster is initialized, so a
+	// field referring to a missing AWS resource fails with an actionable error.
+	References references.Validator
+	// Recorder, when set, records an event on the ControlPlane whenever the
+	// containers of a component change.
+	Recorder record.EventRecorder
+	// Prober, when set, probes the guest apiserver of initialized clusters on
+	// every reconcile for the ControlPlaneReady condition.
+	Prober health.Prober
+	// Guard, when set, checks the tags of the AWS resources of reconciled
+	// clusters and records an event on the ControlPlane when they were changed
+	// outside the operator.
+	Guard guard.Detector
+}
+
+const defaultStuckDeletionTimeout = 10 * time.Minute
+
 type controlPlane struct {
+	options          Options
+	kubeClient       client.Client
 	etcdController   *etcd.Controller
 	masterController *master.Controller
+	addonsController *addons.Controller
+	// availability has the pendingAvailability of each cluster
+	availability sync.Map
+	// notReadyParts has the notReadyPart of the clusters being provisioned
+	notReadyParts sync.Map
 }
 
 // NewController returns a controller for managing VPCs in AWS
-func NewController(kubeClient client.Client) *controlPlane {
+func NewController(kubeClient client.Client, options Options) *controlPlane {
+	if options.StuckDeletionTimeout == 0 {
+		options.StuckDeletionTimeout = defaultStuckDeletionTimeout
+	}
 	return &controlPlane{
+		options:          options,
+		kubeClient:       kubeClient,
 		etcdController:   etcd.New(kubeprovider.New(kubeClient)),
 		masterController: master.New(kubeprovider.New(kubeClient)),
+		addonsController: addons.New(kubeprovider.New(kubeClient)),
 	}
 }
 
@@ -52,21 +118,195 @@ func (c *controlPlane) For() controllers.Object {
 	return &v1alpha1.ControlPlane{}
 }
 
+// Owns returns the types of the objects generated for a ControlPlane
+func (c *controlPlane) Owns() []client.Object {
+	return []client.Object{
+		&appsv1.Deployment{}, &appsv1.StatefulSet{}, &v1.Service{}, &v1.Secret{}, &v1.ConfigMap{},
+		&batchv1.Job{}, &policyv1beta1.PodDisruptionBudget{},
+	}
+}
+
 // Reconcile will check if the resource exists is AWS if it does sync status,
 // else create the resource and then sync status with the ControlPlane.Status
 // object
 func (c *controlPlane) Reconcile(ctx context.Context, object controllers.Object) (res *reconcile.Result, err error) {
-	for _, resource := range []reconciler.Interface{
-		c.etcdController,
-		c.masterController,
+	controlPlane := object.(*v1alpha1.ControlPlane)
+	if err := c.recordAWSClusterName(ctx, controlPlane); err != nil {
+		return nil, err
+	}
+	desired, err := c.desiredStateFor(ctx, controlPlane)
+	if err != nil {
+		return nil, err
+	}
+	// The guest cluster is probed whether the reconcile succeeds or not
+	defer c.probeGuest(ctx, controlPlane, desired)
+	if err := c.adopt(ctx, controlPlane); err != nil {
+		return nil, err
+	}
+	if c.options.References != nil && !controlPlane.Status.Initialized {
+		if err := c.options.References.Validate(ctx, desired); err != nil {
+			controlPlane.Status.Ready = false
+			err = fmt.Errorf("validating references, %w", err)
+			if !failedWith(controlPlane, err) {
+				c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Failed, desired, err.Error()))
+			}
+			return nil, err
+		}
+	}
+	if result, err := c.checkQuotas(ctx, controlPlane, desired); result != nil || err != nil {
+		controlPlane.Status.Ready = false
+		if err != nil && !failedWith(controlPlane, err) {
+			c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Failed, desired, err.Error()))
+		}
+		return result, err
+	}
+	// etcd and master only refer to each other by name and can be reconciled
+	// in parallel once their PriorityClass exists, addons need the master to
+	// be up.
+	for _, stage := range [][]reconciler.Func{
+		{c.reconcilePriorityClass},
+		{c.etcdController.Reconcile, c.masterController.Reconcile},
+		{c.addonsController.Reconcile},
 	} {
-		if err := resource.Reconcile(ctx, object.(*v1alpha1.ControlPlane)); err != nil {
-			return nil, fmt.Errorf("reconciling, %w", err)
+		if err := reconciler.Parallel(ctx, desired, stage...); err != nil {
+			controlPlane.Status.Ready = false
+			err = fmt.Errorf("reconciling, %w", err)
+			if !errors.IsWaitingForSubResource(err) && !failedWith(controlPlane, err) {
+				c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Failed, desired, err.Error()))
+			}
+			return nil, err
 		}
 	}
+	if err := c.auditManifests(ctx, controlPlane); err != nil {
+		return nil, fmt.Errorf("auditing manifests, %w", err)
+	}
+	switch {
+	case !controlPlane.Status.Initialized:
+		c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Provisioned, desired, ""))
+	// Clusters initialized before their version was recorded only record it
+	case controlPlane.Status.Version != "" && controlPlane.Status.Version != desired.Spec.KubernetesVersion:
+		c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Upgraded, desired,
+			fmt.Sprintf("upgraded from %q", controlPlane.Status.Version)))
+	}
+	endpoint, err := c.masterController.Endpoint(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+	controlPlane.Status.Endpoint = endpoint
+	c.detectTampering(ctx, controlPlane)
+	controlPlane.Status.Artifacts = desired.ArtifactsLocation()
+	if err := c.recordProvisioning(ctx, controlPlane); err != nil {
+		return nil, fmt.Errorf("recording provisioning durations, %w", err)
+	}
+	// The estimate is left out rather than reported lower than the actual cost
+	controlPlane.Status.EstimatedHourlyCost, _ = cost.EstimateHourly(desired)
+	controlPlane.Status.Etcd = c.etcdController.Health(ctx, desired)
+	controlPlane.Status.Ready = true
+	controlPlane.Status.Initialized = true
+	controlPlane.Status.ExternalManagedControlPlane = true
+	controlPlane.Status.Version = desired.Spec.KubernetesVersion
 	return results.Created, nil
 }
 
-func (c *controlPlane) Finalize(_ context.Context, _ controllers.Object) (*reconcile.Result, error) {
-	return results.Terminated, nil
+func (c *controlPlane) Finalize(ctx context.Context, object controllers.Object) (*reconcile.Result, error) {
+	controlPlane := object.(*v1alpha1.ControlPlane)
+	// The deletion policy can come from the template, the ControlPlane's own
+	// policy applies when the template was deleted first
+	desired, err := c.withTemplate(ctx, controlPlane)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		desired = controlPlane
+	}
+	if err := c.retain(ctx, controlPlane, desired.Spec.DeletionPolicy); err != nil {
+		return nil, err
+	}
+	pending := sets.NewString(controlPlane.Finalizers...).Delete(fmt.Sprintf(controllers.FinalizerForAWSResources, c.Name()))
+	if pending.Len() == 0 {
+		if desired.Spec.DeletionPolicy.Data != v1alpha1.DeletionPolicyRetain {
+			if err := c.etcdController.Finalize(ctx, controlPlane); err != nil {
+				return nil, err
+			}
+		}
+		c.publish(ctx, controlPlane, notifications.NewEvent(notifications.Deleted, controlPlane, ""))
+		c.forgetGuest(controlPlane)
+		c.forgetProvisioning(controlPlane)
+		c.etcdController.Forget(controlPlane)
+		return results.Terminated, nil
+	}
+	// Other controllers are still finalizing the ControlPlane, wait for them
+	// and let the owners know if the deletion is stuck.
+	if time.Since(controlPlane.DeletionTimestamp.Time) >= c.options.StuckDeletionTimeout {
+		if condition := controlPlane.StatusConditions().GetCondition(v1alpha1.Active); condition == nil || condition.Reason != string(notifications.DeletionStuck) {
+			message := fmt.Sprintf("waiting on finalizers %s", strings.Join(pending.List(), ", "))
+			controlPlane.StatusConditions().MarkFalse(v1alpha1.Active, string(notifications.DeletionStuck), message)
+			c.publish(ctx, controlPlane, notifications.NewEvent(notifications.DeletionStuck, controlPlane, message))
+		}
+	}
+	return nil, errors.WaitingForSubResources
+}
+
+// checkQuotas is skipped once the etcd StatefulSet exists, the instances of
+// the cluster are then counted in the usage of the account. While a requested
+// quota increase is pending the control plane is WaitingOnQuota, and the quota
+// is checked again after a few minutes.
+func (c *controlPlane) checkQuotas(ctx context.Context, controlPlane, desired *v1alpha1.ControlPlane) (*reconcile.Result, error) {
+	if c.options.Quotas == nil {
+		return nil, nil
+	}
+	statefulSet := &appsv1.StatefulSet{}
+	err := c.kubeClient.Get(ctx, object.NamespacedName(etcd.ServiceNameFor(controlPlane.ClusterName()), controlPlane.Namespace), statefulSet)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting etcd statefulset, %w", err)
+	}
+	if err := c.options.Quotas.Check(ctx, desired); err != nil {
+		if quota.IsWaitingOnIncrease(err) {
+			controlPlane.StatusConditions().MarkTrueWithReason(v1alpha1.WaitingOnQuota, "IncreaseRequested", err.Error())
+			return results.WaitingOnQuota, nil
+		}
+		return nil, fmt.Errorf("checking quotas, %w", err)
+	}
+	_ = controlPlane.StatusConditions().ClearCondition(v1alpha1.WaitingOnQuota)
+	return nil, nil
+}
+
+// publish adds the event to the history of the ControlPlane and sends it. It
+// doesn't fail the reconcile when the event can't be sent, lifecycle events
+// are best effort and shouldn't block the cluster from converging.
+func (c *controlPlane) publish(ctx context.Context, controlPlane *v1alpha1.ControlPlane, event notifications.Event) {
+	remember(controlPlane, string(event.Type), event.Message)
+	if c.options.Publisher == nil {
+		return
+	}
+	if err := c.options.Publisher.Publish(ctx, event); err != nil {
+		logging.FromContext(ctx).Errorf("Publishing %s event, %v", event.Type, err)
+	}
+}
+
+// detectTampering doesn't fail the reconcile when the tags can't be checked,
+// the operator may not be allowed to describe the resources.
+func (c *controlPlane) detectTampering(ctx context.Context, controlPlane *v1alpha1.ControlPlane) {
+	if c.options.Guard == nil || c.options.Recorder == nil {
+		return
+	}
+	tampered, err := c.options.Guard.Tampered(ctx, controlPlane)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Checking tags of AWS resources, %v", err)
+		return
+	}
+	if len(tampered) > 0 {
+		c.options.Recorder.Eventf(controlPlane, v1.EventTypeWarning, "TagsTampered",
+			"tags of the apiserver load balancer were changed outside KIT, %s", strings.Join(tampered, ", "))
+	}
+}
+
+// failedWith returns true if the last reconcile already failed with the same
+// error, so a Failed event is only published once per failure.
+func failedWith(controlPlane *v1alpha1.ControlPlane, err error) bool {
+	condition := controlPlane.StatusConditions().GetCondition(v1alpha1.Active)
+	return condition != nil && condition.IsFalse() && strings.HasSuffix(condition.Message, err.Error())
 }
@@ -0,0 +1,42 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// federatedMetrics are the SLIs compared across clusters for regressions,
+// apiserver latencies including its etcd requests and node counts.
+var federatedMetrics = []string{
+	"apiserver_request_duration_seconds_bucket",
+	"apiserver_request_total",
+	"etcd_request_duration_seconds_bucket",
+	"kube_node_info",
+	"kube_node_status_condition",
+}
+
+// withFederation enables the monitoring addon to remote write the federated
+// metrics, unless the ControlPlane configures monitoring itself.
+func (c *controlPlane) withFederation(controlPlane *v1alpha1.ControlPlane) {
+	if c.options.FederationRemoteWriteURL == "" || controlPlane.Spec.Addons.Monitoring != nil {
+		return
+	}
+	controlPlane.Spec.Addons.Monitoring = &v1alpha1.MonitoringAddon{
+		Addon:              v1alpha1.Addon{Enabled: true},
+		RemoteWriteURL:     c.options.FederationRemoteWriteURL,
+		RemoteWriteMetrics: federatedMetrics,
+	}
+}
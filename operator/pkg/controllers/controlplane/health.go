@@ -0,0 +1,225 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/health"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// maxIncidents is how many incidents are kept in the status
+const maxIncidents = 10
+
+var (
+	guestReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_ready",
+		Help:      "1 when the last probe of the guest apiserver through its endpoint passed, 0 otherwise",
+	}, []string{"namespace", "name"})
+	guestUptime = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_uptime_seconds_total",
+		Help:      "Time between passing probes of the guest apiserver and the probe before them, once the cluster was provisioned",
+	}, []string{"namespace", "name"})
+	guestDowntime = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_downtime_seconds_total",
+		Help:      "Time between failed probes of the guest apiserver and the probe before them, once the cluster was provisioned",
+	}, []string{"namespace", "name"})
+	guestAvailability = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_availability_ratio",
+		Help:      "Uptime of the guest apiserver over the time counted in the status of the control plane",
+	}, []string{"namespace", "name"})
+	guestIncidents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_incidents_total",
+		Help:      "Windows of failed probes of the guest apiserver, once the cluster was provisioned",
+	}, []string{"namespace", "name"})
+	guestProbeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "controlplane",
+		Name:      "guest_probe_failures_total",
+		Help:      "Probes of guest apiservers which failed, by the check which failed",
+	}, []string{"check"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(guestReady, guestUptime, guestDowntime, guestAvailability, guestIncidents, guestProbeFailures)
+}
+
+// probeGuest probes the guest apiserver of an initialized cluster, sets the
+// ControlPlaneReady condition and counts the availability.
+func (c *controlPlane) probeGuest(ctx context.Context, controlPlane, desired *v1alpha1.ControlPlane) {
+	if c.options.Prober == nil || !controlPlane.Status.Initialized {
+		return
+	}
+	// The status has a precision of seconds, so the time is counted in
+	// seconds as well
+	now := time.Now().Truncate(time.Second)
+	if desired.Spec.Paused {
+		c.pauseAvailability(controlPlane, now)
+		guestReady.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Set(0)
+		controlPlane.StatusConditions().MarkFalse(v1alpha1.ControlPlaneReady, "Paused", "the master components are scaled down")
+		return
+	}
+	result, err := c.options.Prober.Probe(ctx, desired)
+	c.countAvailability(controlPlane, now, err)
+	if err != nil {
+		guestProbeFailures.WithLabelValues(health.CheckOf(err)).Inc()
+		guestReady.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Set(0)
+		// The message of a failed check varies between probes, e.g. with the
+		// time it took, so the condition is only updated when another check
+		// fails, not to write the status on every probe
+		if condition := controlPlane.StatusConditions().GetCondition(v1alpha1.ControlPlaneReady); condition != nil && condition.IsFalse() &&
+			condition.Reason == "ProbeFailed" && strings.HasPrefix(condition.Message, health.CheckOf(err)+" check failed") {
+			return
+		}
+		controlPlane.StatusConditions().MarkFalse(v1alpha1.ControlPlaneReady, "ProbeFailed", err.Error())
+		return
+	}
+	guestReady.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Set(1)
+	controlPlane.StatusConditions().MarkTrue(v1alpha1.ControlPlaneReady)
+	controlPlane.Status.Guest = &v1alpha1.GuestStatus{Nodes: result.Nodes, ReadyNodes: result.ReadyNodes}
+}
+
+// availabilityCheckpoint is how often the time counted while the result of
+// the probes doesn't change is written to the status
+const availabilityCheckpoint = 10 * time.Minute
+
+// pendingAvailability is the time counted since the availability of a cluster
+// was last written to its status
+type pendingAvailability struct {
+	lastProbe time.Time
+	up        int64
+	down      int64
+}
+
+// countAvailability adds the time since the previous probe to the uptime or
+// the downtime by the result of the probe, once the cluster was provisioned.
+// Probes run on every reconcile, at least every sync period. A longer gap is
+// when the operator wasn't running and isn't counted, as nothing is known of
+// the apiserver then. The counted time is only written to the status when the
+// result of the probes changes or every availabilityCheckpoint, writing it on
+// every probe would trigger another reconcile. The time counted since the
+// last write is lost when the operator restarts.
+func (c *controlPlane) countAvailability(controlPlane *v1alpha1.ControlPlane, now time.Time, err error) {
+	if _, provisioned := controlPlane.Status.Provisioning[ProvisioningControlPlane]; !provisioned {
+		return
+	}
+	availability := controlPlane.Status.Availability
+	if availability == nil {
+		availability = &v1alpha1.AvailabilityStatus{Since: metav1.NewTime(now)}
+		controlPlane.Status.Availability = availability
+	}
+	key := object.NamespacedName(controlPlane.Name, controlPlane.Namespace)
+	pending := &pendingAvailability{lastProbe: now}
+	if value, ok := c.availability.Load(key); ok && availability.LastProbeTime != nil {
+		pending = value.(*pendingAvailability)
+	}
+	previous := pending.lastProbe
+	if now.Sub(previous) > 3*controllers.SyncPeriodFor(c.Name()) {
+		previous = now
+	}
+	seconds := int64(now.Sub(previous) / time.Second)
+	if err != nil {
+		pending.down += seconds
+		guestDowntime.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Add(float64(seconds))
+	} else {
+		pending.up += seconds
+		guestUptime.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Add(float64(seconds))
+	}
+	pending.lastProbe = now
+	c.availability.Store(key, pending)
+	ongoing := len(availability.Incidents) > 0 && availability.Incidents[len(availability.Incidents)-1].End == nil
+	if changed := (err != nil) != ongoing; !changed && availability.LastProbeTime != nil &&
+		now.Sub(availability.LastProbeTime.Time) < availabilityCheckpoint {
+		return
+	}
+	flushAvailability(availability, pending)
+	lastProbe := metav1.NewTime(now)
+	availability.LastProbeTime = &lastProbe
+	switch {
+	case err != nil && !ongoing:
+		availability.Incidents = append(availability.Incidents, v1alpha1.Incident{
+			Start:   metav1.NewTime(previous),
+			Check:   health.CheckOf(err),
+			Message: err.Error(),
+		})
+		if len(availability.Incidents) > maxIncidents {
+			availability.Incidents = availability.Incidents[len(availability.Incidents)-maxIncidents:]
+		}
+		guestIncidents.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Inc()
+	case err == nil && ongoing:
+		availability.Incidents[len(availability.Incidents)-1].End = &lastProbe
+	}
+	if total := availability.UpSeconds + availability.DownSeconds; total > 0 {
+		ratio := float64(availability.UpSeconds) / float64(total)
+		availability.Uptime = fmt.Sprintf("%.3f%%", ratio*100)
+		guestAvailability.WithLabelValues(controlPlane.Namespace, controlPlane.Name).Set(ratio)
+	}
+}
+
+// flushAvailability adds the pending time to the status
+func flushAvailability(availability *v1alpha1.AvailabilityStatus, pending *pendingAvailability) {
+	availability.UpSeconds += pending.up
+	availability.DownSeconds += pending.down
+	pending.up, pending.down = 0, 0
+}
+
+// pauseAvailability stops counting the time of a paused cluster until it's
+// probed again, ending an ongoing incident
+func (c *controlPlane) pauseAvailability(controlPlane *v1alpha1.ControlPlane, now time.Time) {
+	availability := controlPlane.Status.Availability
+	if availability == nil || availability.LastProbeTime == nil {
+		return
+	}
+	if pending, ok := c.availability.LoadAndDelete(object.NamespacedName(controlPlane.Name, controlPlane.Namespace)); ok {
+		flushAvailability(availability, pending.(*pendingAvailability))
+	}
+	availability.LastProbeTime = nil
+	if incidents := availability.Incidents; len(incidents) > 0 && incidents[len(incidents)-1].End == nil {
+		end := metav1.NewTime(now)
+		incidents[len(incidents)-1].End = &end
+	}
+}
+
+// forgetGuest removes the series of a deleted cluster
+func (c *controlPlane) forgetGuest(controlPlane *v1alpha1.ControlPlane) {
+	if c.options.Prober != nil {
+		c.options.Prober.Forget(controlPlane)
+	}
+	c.availability.Delete(object.NamespacedName(controlPlane.Name, controlPlane.Namespace))
+	guestReady.DeleteLabelValues(controlPlane.Namespace, controlPlane.Name)
+	guestUptime.DeleteLabelValues(controlPlane.Namespace, controlPlane.Name)
+	guestDowntime.DeleteLabelValues(controlPlane.Namespace, controlPlane.Name)
+	guestAvailability.DeleteLabelValues(controlPlane.Namespace, controlPlane.Name)
+	guestIncidents.DeleteLabelValues(controlPlane.Namespace, controlPlane.Name)
+}
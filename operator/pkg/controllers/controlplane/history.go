@@ -0,0 +1,38 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxHistory is how many actions are kept in the status
+const maxHistory = 20
+
+// remember adds an action to the history in the status, so what the operator
+// did to a cluster can be found without its logs. An action repeating the
+// last one, e.g. the same failure on every retry, is only kept once.
+func remember(controlPlane *v1alpha1.ControlPlane, reason, message string) {
+	history := controlPlane.Status.History
+	if last := len(history) - 1; last >= 0 && history[last].Reason == reason && history[last].Message == message {
+		return
+	}
+	history = append(history, v1alpha1.HistoryEntry{Time: metav1.Now(), Reason: reason, Message: message})
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	controlPlane.Status.History = history
+}
@@ -0,0 +1,37 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// reconcilePriorityClass creates the PriorityClass of an isolated control
+// plane before etcd and master, which both run with it. It isn't owned by the
+// ControlPlane as it's shared with other clusters of the same priority.
+func (c *controlPlane) reconcilePriorityClass(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	if controlPlane.Spec.Isolation == nil {
+		return nil
+	}
+	if err := c.kubeClient.Create(ctx, isolation.PriorityClassFor(controlPlane)); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating priority class, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Parts of a cluster whose provisioning duration is recorded, ControlPlane is
+// the whole cluster, ready once all the other parts are.
+const (
+	ProvisioningEndpoint     = "Endpoint"
+	ProvisioningEtcd         = "Etcd"
+	ProvisioningAPIServer    = "APIServer"
+	ProvisioningControlPlane = "ControlPlane"
+)
+
+var provisioningDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "kit",
+	Subsystem: "controlplane",
+	Name:      "provisioning_duration_seconds",
+	Help:      "Time from the creation of a ControlPlane until each part of the cluster was first ready",
+	Buckets:   []float64{15, 30, 60, 120, 180, 300, 450, 600, 900, 1200, 1800, 3600},
+}, []string{"part"})
+
+func init() {
+	metrics.Registry.MustRegister(provisioningDuration)
+}
+
+// recordProvisioning adds the parts of the cluster which became ready since
+// the last reconcile to the status and to the provisioning histogram. Parts
+// already in the status aren't observed again. A part is only observed when
+// this process saw it not ready before, so parts of clusters created before a
+// restart, or recorded in a status write which failed, aren't observed with
+// the time since their creation.
+func (c *controlPlane) recordProvisioning(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	ready, err := c.readyParts(ctx, controlPlane)
+	if err != nil {
+		return err
+	}
+	if ready[ProvisioningEndpoint] && ready[ProvisioningEtcd] && ready[ProvisioningAPIServer] {
+		ready[ProvisioningControlPlane] = true
+	}
+	for part, isReady := range ready {
+		if _, ok := controlPlane.Status.Provisioning[part]; ok {
+			continue
+		}
+		key := notReadyPart{NamespacedName: object.NamespacedName(controlPlane.Name, controlPlane.Namespace), part: part}
+		if !isReady {
+			c.notReadyParts.Store(key, true)
+			continue
+		}
+		if controlPlane.Status.Provisioning == nil {
+			controlPlane.Status.Provisioning = map[string]metav1.Duration{}
+		}
+		duration := time.Since(controlPlane.CreationTimestamp.Time).Round(time.Second)
+		controlPlane.Status.Provisioning[part] = metav1.Duration{Duration: duration}
+		if _, seen := c.notReadyParts.LoadAndDelete(key); seen {
+			provisioningDuration.WithLabelValues(part).Observe(duration.Seconds())
+		}
+	}
+	return nil
+}
+
+// notReadyPart is a part of a cluster this process saw not ready
+type notReadyPart struct {
+	types.NamespacedName
+	part string
+}
+
+// forgetProvisioning drops the parts of a deleted cluster
+func (c *controlPlane) forgetProvisioning(controlPlane *v1alpha1.ControlPlane) {
+	for _, part := range []string{ProvisioningEndpoint, ProvisioningEtcd, ProvisioningAPIServer, ProvisioningControlPlane} {
+		c.notReadyParts.Delete(notReadyPart{NamespacedName: object.NamespacedName(controlPlane.Name, controlPlane.Namespace), part: part})
+	}
+}
+
+func (c *controlPlane) readyParts(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (map[string]bool, error) {
+	statefulSet := &appsv1.StatefulSet{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(etcd.ServiceNameFor(controlPlane.ClusterName()), controlPlane.Namespace), statefulSet); err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting etcd statefulset, %w", err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(master.APIServerDeploymentName(controlPlane.ClusterName()), controlPlane.Namespace), deployment); err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting apiserver deployment, %w", err)
+	}
+	return map[string]bool{
+		ProvisioningEndpoint:  controlPlane.Status.Endpoint != "",
+		ProvisioningEtcd:      allReady(statefulSet.Spec.Replicas, statefulSet.Status.ReadyReplicas),
+		ProvisioningAPIServer: allReady(deployment.Spec.Replicas, deployment.Status.ReadyReplicas),
+	}, nil
+}
+
+// allReady is false for workloads scaled to zero, e.g. a paused apiserver
+func allReady(replicas *int32, readyReplicas int32) bool {
+	return replicas != nil && *replicas > 0 && readyReplicas >= *replicas
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RetainedLabelKey is set to the cluster name on the objects retained when
+// the ControlPlane was deleted, so that they can be adopted when the cluster
+// is created again.
+var RetainedLabelKey = v1alpha1.SchemeGroupVersion.Group + "/retained-from"
+
+// retain orphans the objects of the groups the deletion policy retains, by
+// removing the ControlPlane from their owner references, so that the garbage
+// collector keeps them once the ControlPlane is deleted. The policy is the one
+// of the ControlPlane with its template applied.
+func (c *controlPlane) retain(ctx context.Context, controlPlane *v1alpha1.ControlPlane, policy v1alpha1.DeletionPolicy) error {
+	var lists []client.ObjectList
+	if policy.Network == v1alpha1.DeletionPolicyRetain {
+		lists = append(lists, &v1.ServiceList{})
+	}
+	if policy.Compute == v1alpha1.DeletionPolicyRetain {
+		lists = append(lists, &appsv1.DeploymentList{}, &appsv1.StatefulSetList{}, &batchv1.JobList{})
+	}
+	if policy.Data == v1alpha1.DeletionPolicyRetain {
+		lists = append(lists, &v1.SecretList{}, &v1.ConfigMapList{})
+	}
+	return c.forEach(ctx, lists, func(obj client.Object) error {
+		return c.orphan(ctx, controlPlane, obj)
+	}, client.InNamespace(controlPlane.Namespace))
+}
+
+// adopt takes ownership of the objects retained by a previous ControlPlane
+// of the same name, so a re-created cluster reuses them instead of starting
+// over, e.g. keeping its endpoint and certificates.
+func (c *controlPlane) adopt(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	return c.forEach(ctx, []client.ObjectList{
+		&v1.ServiceList{}, &appsv1.DeploymentList{}, &appsv1.StatefulSetList{}, &batchv1.JobList{}, &v1.SecretList{}, &v1.ConfigMapList{},
+	}, func(obj client.Object) error {
+		persisted := obj.DeepCopyObject().(client.Object)
+		labels := obj.GetLabels()
+		delete(labels, RetainedLabelKey)
+		obj.SetLabels(labels)
+		object.WithOwner(controlPlane, obj)
+		if err := c.kubeClient.Patch(ctx, obj, client.MergeFrom(persisted)); err != nil {
+			return fmt.Errorf("adopting %s, %w", obj.GetName(), err)
+		}
+		return nil
+	}, client.InNamespace(controlPlane.Namespace), client.MatchingLabels{RetainedLabelKey: controlPlane.ClusterName()})
+}
+
+func (c *controlPlane) forEach(ctx context.Context, lists []client.ObjectList, f func(client.Object) error, options ...client.ListOption) error {
+	for _, list := range lists {
+		if err := c.kubeClient.List(ctx, list, options...); err != nil {
+			return fmt.Errorf("listing objects, %w", err)
+		}
+		if err := meta.EachListItem(list, func(o runtime.Object) error {
+			return f(o.(client.Object))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *controlPlane) orphan(ctx context.Context, controlPlane *v1alpha1.ControlPlane, obj client.Object) error {
+	owners := []metav1.OwnerReference{}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.UID != controlPlane.UID {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) == len(obj.GetOwnerReferences()) {
+		return nil
+	}
+	persisted := obj.DeepCopyObject().(client.Object)
+	obj.SetOwnerReferences(owners)
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[RetainedLabelKey] = controlPlane.ClusterName()
+	obj.SetLabels(labels)
+	if err := c.kubeClient.Patch(ctx, obj, client.MergeFrom(persisted)); err != nil {
+		return fmt.Errorf("retaining %s, %w", obj.GetName(), err)
+	}
+	return nil
+}
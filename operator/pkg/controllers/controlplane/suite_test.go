@@ -15,25 +15,60 @@ limitations under the License.
 package controlplane_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/authz"
+	"github.com/awslabs/kit/operator/pkg/bugreport"
 	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/addons"
 	"github.com/awslabs/kit/operator/pkg/controllers/controlplane"
 	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
 	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/graph"
+	"github.com/awslabs/kit/operator/pkg/health"
+	"github.com/awslabs/kit/operator/pkg/logs"
+	"github.com/awslabs/kit/operator/pkg/notifications"
+	"github.com/awslabs/kit/operator/pkg/quota"
+	"github.com/awslabs/kit/operator/pkg/references"
 	"github.com/awslabs/kit/operator/pkg/test/environment"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	. "github.com/awslabs/kit/operator/pkg/test/expectations"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	certutil "k8s.io/client-go/util/cert"
 )
 
 var (
@@ -57,7 +92,7 @@ var _ = BeforeSuite(func() {
 	env = environment.New()
 	Expect(env.Start(scheme)).To(Succeed(), "Failed to start environment")
 	kubeClient = env.Client
-	controller = controlplane.NewController(kubeClient)
+	controller = controlplane.NewController(kubeClient, controlplane.Options{})
 })
 
 var _ = AfterSuite(func() {
@@ -103,6 +138,7 @@ var _ = Describe("ControlPlane", func() {
 					master.KubeAdminSecretNameFor(controlPlane.Name),
 					master.KubeSchedulerSecretNameFor(controlPlane.Name),
 					master.KubeControllerManagerSecretNameFor(controlPlane.Name),
+					master.ClusterAPIKubeConfigSecretNameFor(controlPlane.Name),
 				} {
 					ExpectSecretExists(kubeClient, secretName, controlPlane.Namespace)
 				}
@@ -114,9 +150,1064 @@ var _ = Describe("ControlPlane", func() {
 				ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace)
 			})
 		})
+		Context("Paused", func() {
+			It("should scale master components to zero and keep etcd running", func() {
+				controlPlane.Spec.Paused = true
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				for _, name := range []string{
+					master.APIServerDeploymentName(controlPlane.Name),
+					master.KCMDeploymentName(controlPlane.Name),
+					master.SchedulerDeploymentName(controlPlane.Name),
+				} {
+					Expect(*ExpectDeploymentExists(kubeClient, name, controlPlane.Namespace).Spec.Replicas).To(BeZero())
+				}
+				Expect(*ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace).Spec.Replicas).To(BeEquivalentTo(3))
+			})
+		})
+		Context("Field Ownership", func() {
+			It("should reset the fields it owns and keep the labels users add", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				apiServer.Labels = map[string]string{"team": "scale"}
+				apiServer.Spec.Replicas = aws.Int32(1)
+				Expect(kubeClient.Update(context.Background(), apiServer)).To(Succeed())
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer = ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(apiServer.Labels).To(HaveKeyWithValue("team", "scale"))
+				Expect(*apiServer.Spec.Replicas).To(BeEquivalentTo(3))
+			})
+			It("should make the ControlPlane the controller of the objects it generates", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				for _, owned := range []client.Object{
+					ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace),
+					ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace),
+					ExpectSecretExists(kubeClient, master.KubeAdminSecretNameFor(controlPlane.Name), controlPlane.Namespace),
+				} {
+					owner := metav1.GetControllerOf(owned)
+					Expect(owner).ToNot(BeNil())
+					Expect(owner.APIVersion).To(Equal(v1alpha1.SchemeGroupVersion.String()))
+					Expect(owner.UID).To(Equal(controlPlane.UID))
+				}
+			})
+		})
+		Context("Images", func() {
+			It("should run the components from the images provided", func() {
+				controlPlane.Spec.Master.APIServer = &v1alpha1.Component{Image: "registry.example.com/kube-apiserver:dev"}
+				controlPlane.Spec.Etcd.Image = "registry.example.com/etcd@sha256:abc"
+				controlPlane.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "registry-credentials"}}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(apiServer.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/kube-apiserver:dev"))
+				Expect(apiServer.Spec.Template.Spec.ImagePullSecrets).To(Equal(controlPlane.Spec.ImagePullSecrets))
+				scheduler := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(scheduler.Spec.Template.Spec.Containers[0].Image).To(HavePrefix("public.ecr.aws/eks-distro/kubernetes/kube-scheduler"))
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(etcdSet.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/etcd@sha256:abc"))
+				Expect(etcdSet.Spec.Template.Spec.ImagePullSecrets).To(Equal(controlPlane.Spec.ImagePullSecrets))
+			})
+			It("should pull the default images from the image registry", func() {
+				controlPlane.Spec.ImageRegistry = "123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror"
+				controlPlane.Spec.Master.Scheduler = &v1alpha1.Component{Image: "registry.example.com/kube-scheduler:dev"}
+				controlPlane.Spec.Addons.CSRApprover = &v1alpha1.Addon{Enabled: true}
+				controlPlane.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "registry-credentials"}}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(apiServer.Spec.Template.Spec.Containers[0].Image).To(HavePrefix("123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror/eks-distro/kubernetes/kube-apiserver:"))
+				scheduler := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(scheduler.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/kube-scheduler:dev"))
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(etcdSet.Spec.Template.Spec.Containers[0].Image).To(HavePrefix("123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror/eks-distro/etcd-io/etcd:"))
+				job := &batchv1.Job{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "csr-approver")}, job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror/bitnami/kubectl:1.20"))
+				Expect(job.Spec.Template.Spec.ImagePullSecrets).To(Equal(controlPlane.Spec.ImagePullSecrets))
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "csr-approver")}, files)).To(Succeed())
+				Expect(files.Data["kubelet-csr-approver.yaml"]).To(ContainSubstring("image: 123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror/postfinance/kubelet-csr-approver:v0.2.2"))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				controlPlane.Spec.Addons.EBSCSIDriver = &v1alpha1.Addon{Enabled: true}
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "ebs-csi-driver")}, files)).To(Succeed())
+				Expect(files.Data["aws-ebs-csi-driver.yaml"]).To(ContainSubstring("image: 123456789012.dkr.ecr.us-west-2.amazonaws.com/mirror/sig-storage/csi-provisioner:v2.1.1"))
+				Expect(files.Data["aws-ebs-csi-driver.yaml"]).ToNot(ContainSubstring("k8s.gcr.io"))
+			})
+		})
+		Context("Binaries", func() {
+			It("should download and run the apiserver binary from the URL provided", func() {
+				controlPlane.Spec.Master.APIServer = &v1alpha1.Component{BinaryURL: "https://example.com/ci/kube-apiserver"}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				spec := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec
+				Expect(spec.InitContainers).To(HaveLen(1))
+				Expect(spec.InitContainers[0].Args[0]).To(ContainSubstring("https://example.com/ci/kube-apiserver"))
+				Expect(spec.Containers[0].Command).To(Equal([]string{"/opt/kit/bin/kube-apiserver"}))
+				Expect(ExpectDeploymentExists(kubeClient, master.KCMDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec.InitContainers).To(BeEmpty())
+			})
+		})
+		Context("Feature Gates", func() {
+			It("should pass feature gates to all master components and runtime config to the apiserver", func() {
+				controlPlane.Spec.Master.FeatureGates = map[string]bool{"EphemeralContainers": true, "CSIStorageCapacity": false}
+				controlPlane.Spec.Master.RuntimeConfig = map[string]string{"storage.k8s.io/v1alpha1": "true"}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				for _, name := range []string{
+					master.APIServerDeploymentName(controlPlane.Name),
+					master.KCMDeploymentName(controlPlane.Name),
+					master.SchedulerDeploymentName(controlPlane.Name),
+				} {
+					Expect(ExpectDeploymentExists(kubeClient, name, controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+						To(ContainElement("--feature-gates=CSIStorageCapacity=false,EphemeralContainers=true"))
+				}
+				Expect(ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+					To(ContainElement("--runtime-config=storage.k8s.io/v1alpha1=true"))
+				Expect(ExpectDeploymentExists(kubeClient, master.KCMDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+					ToNot(ContainElement(HavePrefix("--runtime-config")))
+			})
+		})
+		Context("Scheduler Config", func() {
+			It("should mount an inline scheduler config in the scheduler", func() {
+				controlPlane.Spec.Master.SchedulerConfig = &v1alpha1.SchedulerConfig{Inline: "apiVersion: kubescheduler.config.k8s.io/v1beta1"}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				configMap := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: master.SchedulerConfigNameFor(controlPlane.Name)}, configMap)).To(Succeed())
+				Expect(configMap.Data).To(HaveKeyWithValue("config.yaml", controlPlane.Spec.Master.SchedulerConfig.Inline))
+				spec := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec
+				Expect(spec.Containers[0].Args).To(ContainElement("--config=/etc/kubernetes/config/scheduler-profile/config.yaml"))
+				Expect(spec.Volumes[len(spec.Volumes)-1].ConfigMap.Name).To(Equal(master.SchedulerConfigNameFor(controlPlane.Name)))
+				checksum := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Annotations[object.ChecksumAnnotationKey]
+				Expect(checksum).ToNot(BeEmpty())
+
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				controlPlane.Spec.Master.SchedulerConfig.Inline = "apiVersion: kubescheduler.config.k8s.io/v1beta2"
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				Expect(ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Annotations[object.ChecksumAnnotationKey]).ToNot(Equal(checksum))
+			})
+			It("should mount a referenced scheduler config in the scheduler", func() {
+				controlPlane.Spec.Master.SchedulerConfig = &v1alpha1.SchedulerConfig{ConfigMapRef: &v1.ConfigMapKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: "profiles"},
+					Key:                  "binpacking.yaml",
+				}}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				spec := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec
+				volume := spec.Volumes[len(spec.Volumes)-1]
+				Expect(volume.ConfigMap.Name).To(Equal("profiles"))
+				Expect(volume.ConfigMap.Items).To(Equal([]v1.KeyToPath{{Key: "binpacking.yaml", Path: "config.yaml"}}))
+			})
+		})
+		Context("Cloud Provider", func() {
+			It("should run the cloud controller manager for an external cloud provider", func() {
+				controlPlane.Spec.Master.CloudProvider = v1alpha1.CloudProviderExternal
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(ExpectDeploymentExists(kubeClient, master.KCMDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+					To(ContainElement("--cloud-provider=external"))
+				Expect(ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+					ToNot(ContainElement(HavePrefix("--cloud-provider")))
+				ExpectDeploymentExists(kubeClient, master.CCMDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				ExpectSecretExists(kubeClient, master.KubeCloudControllerManagerSecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				job := &batchv1.Job{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "cloud-controller-manager-rbac")}, job)).To(Succeed())
+			})
+			It("should use the in-tree provider for aws", func() {
+				controlPlane.Spec.Master.CloudProvider = v1alpha1.CloudProviderAWS
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				for _, name := range []string{
+					master.APIServerDeploymentName(controlPlane.Name),
+					master.KCMDeploymentName(controlPlane.Name),
+				} {
+					Expect(ExpectDeploymentExists(kubeClient, name, controlPlane.Namespace).Spec.Template.Spec.Containers[0].Args).
+						To(ContainElement("--cloud-provider=aws"))
+				}
+				ExpectNotFound(kubeClient, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: master.CCMDeploymentName(controlPlane.Name), Namespace: controlPlane.Namespace}})
+			})
+		})
+		Context("Konnectivity", func() {
+			It("should run the konnectivity server next to the apiserver and the agents in the guest cluster", func() {
+				controlPlane.Spec.Master.Konnectivity = &v1alpha1.Konnectivity{Enabled: true}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				spec := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec
+				Expect(spec.Containers).To(HaveLen(2))
+				Expect(spec.Containers[0].Args).To(ContainElement("--egress-selector-config-file=/etc/kubernetes/konnectivity/egress-selector-configuration.yaml"))
+				Expect(spec.Containers[1].Name).To(Equal("konnectivity-server"))
+				Expect(spec.Containers[1].Args).To(ContainElements("--server-id=$(POD_NAME)", "--server-count=3"))
+				Expect(spec.Containers[1].Env[0].ValueFrom.FieldRef.FieldPath).To(Equal("metadata.name"))
+				config := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: master.KonnectivityConfigNameFor(controlPlane.Name)}, config)).To(Succeed())
+				Expect(config.Data["egress-selector-configuration.yaml"]).To(ContainSubstring("konnectivity-server.socket"))
+				service := ExpectServiceExists(kubeClient, master.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(service.Spec.Ports).To(HaveLen(2))
+				Expect(service.Spec.Ports[1].Port).To(BeNumerically("==", master.KonnectivityAgentPort))
+				job := &batchv1.Job{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "konnectivity-agent")}, job)).To(Succeed())
+			})
+		})
+		Context("Storage", func() {
+			It("should put the etcd data on volumes of the storage class provided", func() {
+				// Volume claim templates are kept from the StatefulSet of a
+				// previous test with the same name
+				controlPlane.Name = "storagecluster"
+				controlPlane.Spec.Etcd.Storage = &v1alpha1.ETCDStorage{Type: "io2", IOPS: 10000}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				storageClass := &storagev1.StorageClass{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Name: etcd.StorageClassNameFor(controlPlane.Spec.Etcd.Storage)}, storageClass)).To(Succeed())
+				Expect(storageClass.Parameters).To(Equal(map[string]string{"type": "io2", "iops": "10000"}))
+				statefulSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+				Expect(*statefulSet.Spec.VolumeClaimTemplates[0].Spec.StorageClassName).To(Equal(storageClass.Name))
+				Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String()).To(Equal("20Gi"))
+				for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+					Expect(volume.HostPath).To(BeNil())
+				}
+			})
+		})
+		Context("Isolation", func() {
+			It("should schedule the components on the node pool with their priority class and disruption budgets", func() {
+				controlPlane.Spec.Isolation = &v1alpha1.Isolation{NodePool: "tenant-a", Priority: aws.Int32(5000)}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				priorityClass := &schedulingv1.PriorityClass{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Name: "kit-control-plane-5000"}, priorityClass)).To(Succeed())
+				Expect(priorityClass.Value).To(BeNumerically("==", 5000))
+				for _, spec := range []v1.PodSpec{
+					ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec,
+					ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec,
+					ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace).Spec.Template.Spec,
+				} {
+					Expect(spec.NodeSelector).To(HaveKeyWithValue(object.NodePoolLabelKey, "tenant-a"))
+					Expect(spec.Tolerations).To(ContainElement(v1.Toleration{
+						Key: object.NodePoolLabelKey, Operator: v1.TolerationOpEqual, Value: "tenant-a", Effect: v1.TaintEffectNoSchedule,
+					}))
+					Expect(spec.PriorityClassName).To(Equal(priorityClass.Name))
+				}
+				for _, name := range []string{
+					master.APIServerDeploymentName(controlPlane.Name),
+					master.KCMDeploymentName(controlPlane.Name),
+					master.SchedulerDeploymentName(controlPlane.Name),
+					etcd.ServiceNameFor(controlPlane.Name),
+				} {
+					Expect(kubeClient.Get(context.Background(), types.NamespacedName{Name: name, Namespace: controlPlane.Namespace}, &policyv1beta1.PodDisruptionBudget{})).To(Succeed())
+				}
+			})
+		})
+		Context("Architecture", func() {
+			It("should schedule the components on nodes of their architecture", func() {
+				controlPlane.Spec.Master.Architecture = "arm64"
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				deployment := &appsv1.Deployment{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: master.APIServerDeploymentName(controlPlane.Name)}, deployment)).To(Succeed())
+				Expect(deployment.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(v1.LabelArchStable, "arm64"))
+				statefulSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(statefulSet.Spec.Template.Spec.NodeSelector).ToNot(HaveKey(v1.LabelArchStable))
+			})
+		})
+		Context("Addons", func() {
+			It("should install enabled addons in the guest cluster", func() {
+				controlPlane.Spec.Addons.EBSCSIDriver = &v1alpha1.Addon{Enabled: true}
+				controlPlane.Spec.Addons.NVIDIADevicePlugin = &v1alpha1.Addon{Enabled: true}
+				controlPlane.Spec.Addons.CSRApprover = &v1alpha1.Addon{Enabled: true}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				job := &batchv1.Job{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "ebs-csi-driver")}, job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Command[2]).ToNot(ContainSubstring("https://"))
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "ebs-csi-driver")}, files)).To(Succeed())
+				Expect(files.Data["aws-ebs-csi-driver.yaml"]).To(ContainSubstring("image: k8s.gcr.io/provider-aws/aws-ebs-csi-driver:v1.1.0"))
+				Expect(files.Data["storageclass.yaml"]).To(ContainSubstring("type: gp3"))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "nvidia-device-plugin")}, job)).To(Succeed())
+				Expect(job.Spec.Template.Spec.Containers[0].Command[2]).ToNot(ContainSubstring("https://"))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "nvidia-device-plugin")}, files)).To(Succeed())
+				Expect(files.Data["nvidia-device-plugin.yml"]).To(ContainSubstring("image: nvcr.io/nvidia/k8s-device-plugin:v0.9.0"))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "csr-approver")}, files)).To(Succeed())
+				Expect(files.Data["node-client.yaml"]).To(ContainSubstring("certificatesigningrequests:nodeclient"))
+				providerRegex := regexp.MustCompile(`name: PROVIDER_REGEX\n\s*value: (.*)\n`).FindStringSubmatch(files.Data["kubelet-csr-approver.yaml"])
+				Expect(providerRegex).To(HaveLen(2))
+				hostnames := regexp.MustCompile(providerRegex[1])
+				Expect(hostnames.MatchString("ip-10-0-0-1.ec2.internal")).To(BeTrue())
+				Expect(hostnames.MatchString("ip-10-0-0-1.us-west-2.compute.internal")).To(BeTrue())
+				Expect(hostnames.MatchString("ip-10-0-0-1.example.com")).To(BeFalse())
+			})
+			It("should remote write metrics from the monitoring addon labeled with the cluster name", func() {
+				controlPlane.Spec.Addons.Monitoring = &v1alpha1.MonitoringAddon{
+					Addon:          v1alpha1.Addon{Enabled: true},
+					RemoteWriteURL: "https://aps-workspaces.us-west-2.amazonaws.com/workspaces/ws-1234/api/v1/remote_write",
+				}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "monitoring")}, files)).To(Succeed())
+				Expect(files.Data["prometheus.yml"]).To(ContainSubstring("cluster: default/testcluster"))
+				Expect(files.Data["prometheus.yml"]).To(ContainSubstring("region: us-west-2"))
+				Expect(files.Data["monitoring.yaml"]).To(ContainSubstring("grafana/grafana:"))
+				Expect(files.Data["apiserver.json"]).To(ContainSubstring("apiserver_request_duration_seconds_bucket"))
+				Expect(files.Data["etcd.json"]).To(ContainSubstring("etcd_request_duration_seconds_bucket"))
+			})
+			It("should remote write metrics from the monitoring addon with the IAM role of its service account", func() {
+				controlPlane.Spec.Addons.Monitoring = &v1alpha1.MonitoringAddon{
+					Addon:          v1alpha1.Addon{Enabled: true},
+					RemoteWriteURL: "https://aps-workspaces.us-west-2.amazonaws.com/workspaces/ws-1234/api/v1/remote_write",
+					RoleARN:        "arn:aws:iam::123456789012:role/prometheus",
+				}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "monitoring")}, files)).To(Succeed())
+				Expect(files.Data["serviceaccount.yaml"]).To(ContainSubstring("eks.amazonaws.com/role-arn: arn:aws:iam::123456789012:role/prometheus"))
+				Expect(files.Data["monitoring.yaml"]).ToNot(ContainSubstring("kind: ServiceAccount\nmetadata:\n  name: prometheus\n"))
+			})
+			It("should install kube-proxy in the selected mode", func() {
+				controlPlane.Spec.Addons.KubeProxy = &v1alpha1.KubeProxyAddon{Addon: v1alpha1.Addon{Enabled: true}, Mode: v1alpha1.KubeProxyModeIPVS}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "kube-proxy")}, files)).To(Succeed())
+				Expect(files.Data["kube-proxy.yaml"]).To(ContainSubstring(`mode: "ipvs"`))
+				Expect(files.Data["kube-proxy.yaml"]).To(ContainSubstring("image: public.ecr.aws/eks-distro/kubernetes/kube-proxy:v1.20.7-eks-1-20-4"))
+				job := &batchv1.Job{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "kube-proxy")}, job)).To(Succeed())
+				checksum := job.Annotations[object.ChecksumAnnotationKey]
+
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				controlPlane.Spec.Addons.KubeProxy.Mode = v1alpha1.KubeProxyModeIPTables
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "kube-proxy")}, files)).To(Succeed())
+				Expect(files.Data["kube-proxy.yaml"]).To(ContainSubstring(`mode: "iptables"`))
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "kube-proxy")}, job)).To(Succeed())
+				Expect(job.Annotations[object.ChecksumAnnotationKey]).ToNot(Equal(checksum))
+			})
+			It("should not install kube-proxy when its mode is none", func() {
+				controlPlane.Spec.Addons.KubeProxy = &v1alpha1.KubeProxyAddon{Addon: v1alpha1.Addon{Enabled: true}, Mode: v1alpha1.KubeProxyModeNone}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				ExpectNotFound(kubeClient, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: addons.JobNameFor(controlPlane.Name, "kube-proxy"), Namespace: controlPlane.Namespace}})
+			})
+		})
+		Context("Federation", func() {
+			It("should remote write the key metrics of clusters without monitoring", func() {
+				federated := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					FederationRemoteWriteURL: "https://aps-workspaces.us-east-1.amazonaws.com/workspaces/ws-1234/api/v1/remote_write",
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), federated, client.ObjectKeyFromObject(controlPlane))
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), federated, client.ObjectKeyFromObject(controlPlane))
+				files := &v1.ConfigMap{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: addons.JobNameFor(controlPlane.Name, "monitoring")}, files)).To(Succeed())
+				Expect(files.Data["prometheus.yml"]).To(ContainSubstring("ws-1234"))
+				Expect(files.Data["prometheus.yml"]).To(ContainSubstring("regex: (apiserver_request_duration_seconds_bucket|"))
+			})
+		})
+		Context("Lifecycle Events", func() {
+			It("should publish an event when the cluster is provisioned, upgraded and deleted", func() {
+				publisher := &fakePublisher{}
+				notifying := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Publisher: publisher,
+				})}
+				controlPlane.Spec.KubernetesVersion = "1.19"
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Expect(publisher.types()).To(Equal([]notifications.EventType{notifications.Provisioned}))
+
+				// A cluster without a recorded version wasn't upgraded
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				controlPlane.Status.Version = ""
+				Expect(kubeClient.Status().Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Expect(publisher.types()).To(Equal([]notifications.EventType{notifications.Provisioned}))
+
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Version).To(Equal("1.19"))
+				controlPlane.Spec.KubernetesVersion = "1.20"
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Expect(publisher.types()).To(Equal([]notifications.EventType{notifications.Provisioned, notifications.Upgraded}))
+				Expect(publisher.events[1].KubernetesVersion).To(Equal("1.20"))
+
+				Expect(kubeClient.Delete(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Expect(publisher.types()).To(Equal([]notifications.EventType{notifications.Provisioned, notifications.Upgraded, notifications.Deleted}))
+				Expect(publisher.events[2].Cluster).To(Equal(controlPlane.Name))
+			})
+		})
+		Context("Webhook Notifications", func() {
+			It("should notify the namespace's webhook when a deletion is stuck", func() {
+				requests := make(chan map[string]string, 1)
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					payload := map[string]string{}
+					Expect(json.NewDecoder(r.Body).Decode(&payload)).To(Succeed())
+					requests <- payload
+				}))
+				defer server.Close()
+				notifying := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Publisher:            notifications.NewWebhook("http://unused", map[string]string{controlPlane.Namespace: server.URL}),
+					StuckDeletionTimeout: time.Nanosecond,
+				})}
+				controlPlane.Finalizers = []string{"test.kit.k8s.sh/hold"}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Delete(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Eventually(requests).Should(Receive(HaveKeyWithValue("text", ContainSubstring("test.kit.k8s.sh/hold"))))
+				// The deletion is only reported once
+				ExpectReconcile(context.Background(), notifying, client.ObjectKeyFromObject(controlPlane))
+				Consistently(requests).ShouldNot(Receive())
+			})
+		})
+		Context("Quota Preflight", func() {
+			It("should fail before creating any objects when the quota is insufficient", func() {
+				publisher := &fakePublisher{}
+				checker := &fakeChecker{err: &quota.InsufficientError{Code: quota.StandardOnDemandVCPUs, Required: 12, Usage: 1000, Limit: 1000}}
+				preflighted := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Publisher: publisher,
+					Quotas:    checker,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				_, err := preflighted.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(controlPlane)})
+				Expect(quota.IsInsufficient(err)).To(BeTrue())
+				ExpectNotFound(kubeClient, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: etcd.ServiceNameFor(controlPlane.Name), Namespace: controlPlane.Namespace}})
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.Active).Message).To(ContainSubstring("insufficient quota L-1216C47A"))
+				Expect(publisher.types()).To(Equal([]notifications.EventType{notifications.Failed}))
+
+				// Once the cluster's objects exist the quota isn't checked again
+				checker.err = nil
+				ExpectReconcile(context.Background(), preflighted, client.ObjectKeyFromObject(controlPlane))
+				checker.err = &quota.InsufficientError{Code: quota.StandardOnDemandVCPUs}
+				ExpectReconcile(context.Background(), preflighted, client.ObjectKeyFromObject(controlPlane))
+				Expect(checker.checks).To(Equal(2))
+			})
+			It("should wait on a requested quota increase", func() {
+				publisher := &fakePublisher{}
+				checker := &fakeChecker{err: &quota.InsufficientError{Code: quota.StandardOnDemandVCPUs, Required: 12, Usage: 1000, Limit: 1000, RequestID: "request-1"}}
+				preflighted := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Publisher: publisher,
+					Quotas:    checker,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				result, err := preflighted.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(controlPlane)})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically(">=", time.Minute))
+				ExpectNotFound(kubeClient, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: etcd.ServiceNameFor(controlPlane.Name), Namespace: controlPlane.Namespace}})
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				waiting := controlPlane.StatusConditions().GetCondition(v1alpha1.WaitingOnQuota)
+				Expect(waiting).ToNot(BeNil())
+				Expect(waiting.Message).To(ContainSubstring("request-1"))
+				Expect(publisher.events).To(BeEmpty())
+
+				checker.err = nil
+				ExpectReconcile(context.Background(), preflighted, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.WaitingOnQuota)).To(BeNil())
+			})
+		})
+		Context("References", func() {
+			It("should fail before creating any objects when a reference is invalid", func() {
+				validator := &fakeValidator{err: &references.InvalidError{Field: "spec.artifactBucket", Value: "missing", Reason: "bucket missing doesn't exist"}}
+				validated := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					References: validator,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				_, err := validated.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(controlPlane)})
+				Expect(references.IsInvalid(err)).To(BeTrue())
+				ExpectNotFound(kubeClient, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: etcd.ServiceNameFor(controlPlane.Name), Namespace: controlPlane.Namespace}})
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.Active).Message).To(ContainSubstring(`invalid spec.artifactBucket "missing"`))
+
+				validator.err = nil
+				ExpectReconcile(context.Background(), validated, client.ObjectKeyFromObject(controlPlane))
+				ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+			})
+		})
+		Context("Guest Health", func() {
+			It("should set ControlPlaneReady from probes of the guest apiserver", func() {
+				prober := &fakeProber{result: &health.Result{Nodes: 3, ReadyNodes: 2}}
+				probed := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Prober: prober,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(prober.probes).To(BeZero())
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(prober.probes).To(Equal(1))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.ControlPlaneReady).IsTrue()).To(BeTrue())
+				Expect(controlPlane.Status.Guest).To(Equal(&v1alpha1.GuestStatus{Nodes: 3, ReadyNodes: 2}))
+
+				prober.err = &health.ProbeError{Check: health.CheckReadyz, Err: fmt.Errorf("etcd failed")}
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				condition := controlPlane.StatusConditions().GetCondition(v1alpha1.ControlPlaneReady)
+				Expect(condition.IsFalse()).To(BeTrue())
+				Expect(condition.Message).To(Equal("readyz check failed, etcd failed"))
+
+				prober.err = &health.ProbeError{Check: health.CheckReadyz, Err: fmt.Errorf("etcd timed out")}
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.ControlPlaneReady).Message).To(Equal("readyz check failed, etcd failed"))
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.Active).IsTrue()).To(BeTrue())
+			})
+			It("should record incidents once the control plane is provisioned", func() {
+				prober := &fakeProber{result: &health.Result{}}
+				probed := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Prober: prober,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Availability).To(BeNil())
+
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				etcdSet.Status.Replicas = *etcdSet.Spec.Replicas
+				etcdSet.Status.ReadyReplicas = *etcdSet.Spec.Replicas
+				Expect(kubeClient.Status().Update(context.Background(), etcdSet)).To(Succeed())
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				apiServer.Status.Replicas = *apiServer.Spec.Replicas
+				apiServer.Status.ReadyReplicas = *apiServer.Spec.Replicas
+				Expect(kubeClient.Status().Update(context.Background(), apiServer)).To(Succeed())
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Availability).ToNot(BeNil())
+				Expect(controlPlane.Status.Availability.LastProbeTime).ToNot(BeNil())
+				Expect(controlPlane.Status.Availability.Incidents).To(BeEmpty())
+				lastProbeTime := controlPlane.Status.Availability.LastProbeTime
+				time.Sleep(time.Second)
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Availability.LastProbeTime).To(Equal(lastProbeTime))
+
+				prober.err = &health.ProbeError{Check: health.CheckNodes, Err: fmt.Errorf("timed out")}
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Availability.Incidents).To(HaveLen(1))
+				Expect(controlPlane.Status.Availability.Incidents[0].Check).To(Equal(health.CheckNodes))
+				Expect(controlPlane.Status.Availability.Incidents[0].End).To(BeNil())
+
+				prober.err = nil
+				ExpectReconcile(context.Background(), probed, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Availability.Incidents).To(HaveLen(1))
+				Expect(controlPlane.Status.Availability.Incidents[0].End).ToNot(BeNil())
+			})
+		})
+		Context("Stalled", func() {
+			AfterEach(func() {
+				controllers.StallTimeout = 30 * time.Minute
+			})
+			It("should mark the control plane as stalled while it waits on its endpoint", func() {
+				controllers.StallTimeout = 0
+				genController := &controllers.GenericController{Controller: controller, Client: kubeClient}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), genController, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				stalled := controlPlane.StatusConditions().GetCondition(v1alpha1.Stalled)
+				Expect(stalled).ToNot(BeNil())
+				Expect(stalled.IsTrue()).To(BeTrue())
+				Expect(stalled.Message).To(ContainSubstring("endpoint"))
+
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), genController, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.StatusConditions().GetCondition(v1alpha1.Stalled)).To(BeNil())
+			})
+		})
+		Context("Dependency Graph", func() {
+			It("should report the state of each object of the control plane", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				dependencies, err := graph.For(context.Background(), kubeClient, controlPlane)
+				Expect(err).ToNot(HaveOccurred())
+				states := map[string]graph.State{}
+				for _, node := range dependencies.Nodes {
+					states[node.ID()] = node.State
+				}
+				Expect(states).To(HaveKeyWithValue("Service/"+master.ServiceNameFor(controlPlane.Name), graph.Ready))
+				Expect(states).To(HaveKeyWithValue("Secret/"+master.KubeAdminSecretNameFor(controlPlane.Name), graph.Ready))
+				// There are no nodes to run the pods in the test environment
+				Expect(states).To(HaveKeyWithValue("Deployment/"+master.APIServerDeploymentName(controlPlane.Name), graph.Pending))
+				Expect(dependencies.DOT()).To(ContainSubstring(fmt.Sprintf("%q -> %q",
+					"Deployment/"+master.APIServerDeploymentName(controlPlane.Name), "Deployment/"+master.KCMDeploymentName(controlPlane.Name))))
+			})
+			It("should report what each object is waiting on", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				dependencies, err := graph.For(context.Background(), kubeClient, controlPlane)
+				Expect(err).ToNot(HaveOccurred())
+				for _, node := range dependencies.Nodes {
+					Expect(node.Created).ToNot(BeNil())
+					if node.ID() == "Deployment/"+master.APIServerDeploymentName(controlPlane.Name) {
+						Expect(node.WaitingOn).To(ConsistOf("StatefulSet/" + etcd.ServiceNameFor(controlPlane.Name)))
+					}
+				}
+				Expect(dependencies.Text(time.Now())).To(MatchRegexp(`Deployment/%s\s+Pending\s+\S+\s+-\s+StatefulSet/%s`,
+					master.APIServerDeploymentName(controlPlane.Name), etcd.ServiceNameFor(controlPlane.Name)))
+			})
+		})
+		Context("Deletion Policy", func() {
+			It("should keep the objects of retained groups when the control plane is deleted", func() {
+				controlPlane.Spec.DeletionPolicy.Data = v1alpha1.DeletionPolicyRetain
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Delete(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				secret := ExpectSecretExists(kubeClient, master.RootCASecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(secret.OwnerReferences).To(BeEmpty())
+				deployment := &appsv1.Deployment{}
+				Expect(kubeClient.Get(context.Background(), types.NamespacedName{Namespace: controlPlane.Namespace, Name: master.APIServerDeploymentName(controlPlane.Name)}, deployment)).To(Succeed())
+				Expect(deployment.OwnerReferences).To(HaveLen(1))
+			})
+			It("should keep the objects of the groups its template retains", func() {
+				template := &v1alpha1.ClusterTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "retained", Namespace: controlPlane.Namespace},
+					Spec: v1alpha1.ClusterTemplateSpec{ControlPlane: v1alpha1.ControlPlaneSpec{
+						DeletionPolicy: v1alpha1.DeletionPolicy{Data: v1alpha1.DeletionPolicyRetain},
+					}},
+				}
+				controlPlane.Spec.Template = template.Name
+				ExpectCreated(kubeClient, template, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Delete(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				secret := ExpectSecretExists(kubeClient, master.RootCASecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(secret.OwnerReferences).To(BeEmpty())
+			})
+			It("should adopt the retained objects when the control plane is created again", func() {
+				controlPlane.Spec.DeletionPolicy.Data = v1alpha1.DeletionPolicyRetain
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Delete(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				ExpectNotFound(kubeClient, controlPlane)
+				recreated := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: controlPlane.Name, Namespace: controlPlane.Namespace}}
+				ExpectCreated(kubeClient, recreated)
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(recreated))
+				secret := ExpectSecretExists(kubeClient, master.RootCASecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(secret.OwnerReferences).To(HaveLen(1))
+				Expect(secret.OwnerReferences[0].UID).To(Equal(recreated.UID))
+				Expect(secret.Labels).ToNot(HaveKey(controlplane.RetainedLabelKey))
+			})
+		})
+		Context("Logs", func() {
+			It("should stream the logs of the pods of a component", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				// The test environment runs no pods and has no kubelet to serve
+				// their logs, the fake clientset returns "fake logs"
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: apiServer.Name + "-abc12", Namespace: controlPlane.Namespace, Labels: apiServer.Spec.Selector.MatchLabels},
+					Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "apiserver"}}},
+				}
+				handler := logs.NewHandler(fake.NewSimpleClientset(apiServer, pod))
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s%s/%s?component=apiserver&since=10m", logs.Path, controlPlane.Namespace, controlPlane.Name), nil))
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(Equal(fmt.Sprintf("[%s/apiserver] fake logs\n", pod.Name)))
+			})
+			It("should reject unknown components", func() {
+				recorder := httptest.NewRecorder()
+				logs.NewHandler(fake.NewSimpleClientset()).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, logs.Path+"default/test?component=kubelet", nil))
+				Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			})
+			It("should only serve the users allowed to get the logs of the ControlPlane", func() {
+				clientSet := fake.NewSimpleClientset()
+				var access *authorizationv1.SubjectAccessReview
+				clientSet.PrependReactor("create", "tokenreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+					review := action.(clientgotesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+					review.Status.Authenticated = review.Spec.Token == "valid"
+					review.Status.User.Username = "alice"
+					return true, review, nil
+				})
+				clientSet.PrependReactor("create", "subjectaccessreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+					access = action.(clientgotesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+					access.Status.Allowed = access.Spec.User == "alice" && access.Spec.ResourceAttributes.Name == "allowed"
+					return true, access, nil
+				})
+				next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+				handler := authz.NewHandler(clientSet, logs.Path, "logs", next)
+				for _, test := range []struct {
+					token string
+					name  string
+					code  int
+				}{
+					{"", "allowed", http.StatusUnauthorized},
+					{"invalid", "allowed", http.StatusUnauthorized},
+					{"valid", "denied", http.StatusForbidden},
+					{"valid", "allowed", http.StatusOK},
+				} {
+					recorder := httptest.NewRecorder()
+					request := httptest.NewRequest(http.MethodGet, logs.Path+"default/"+test.name, nil)
+					if test.token != "" {
+						request.Header.Set("Authorization", "Bearer "+test.token)
+					}
+					handler.ServeHTTP(recorder, request)
+					Expect(recorder.Code).To(Equal(test.code), "token %q, name %q", test.token, test.name)
+				}
+				Expect(access.Spec.ResourceAttributes).To(Equal(&authorizationv1.ResourceAttributes{
+					Namespace: "default", Verb: "get", Group: "kit.k8s.sh", Resource: "controlplanes", Subresource: "logs", Name: "allowed",
+				}))
+			})
+		})
+		Context("Proxy", func() {
+			It("should route the egress of the components reaching outside the cluster through the proxy", func() {
+				controlPlane.Spec.Proxy = &v1alpha1.Proxy{HTTPSProxy: "http://proxy.corp:3128", NoProxy: []string{"10.0.0.0/16"}}
+				controlPlane.Spec.Network = &v1alpha1.Network{PodCIDR: "192.168.0.0/16"}
+				vpcCIDRs := master.VPCCIDRs
+				master.VPCCIDRs = func() []string { return []string{"10.20.0.0/16"} }
+				defer func() { master.VPCCIDRs = vpcCIDRs }()
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				for _, name := range []string{master.APIServerDeploymentName(controlPlane.Name), master.KCMDeploymentName(controlPlane.Name)} {
+					deployment := ExpectDeploymentExists(kubeClient, name, controlPlane.Namespace)
+					env := deployment.Spec.Template.Spec.Containers[0].Env
+					Expect(env).To(ContainElements(
+						v1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy.corp:3128"},
+						v1.EnvVar{Name: "https_proxy", Value: "http://proxy.corp:3128"},
+						v1.EnvVar{Name: "NO_PROXY", Value: "localhost,127.0.0.1,.svc,.cluster.local,10.96.0.0/12,192.168.0.0/16,10.20.0.0/16,elb-endpoint,10.0.0.0/16"},
+					))
+					for _, variable := range env {
+						Expect(variable.Name).ToNot(Equal("HTTP_PROXY"))
+					}
+				}
+				scheduler := ExpectDeploymentExists(kubeClient, master.SchedulerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				for _, variable := range scheduler.Spec.Template.Spec.Containers[0].Env {
+					Expect(variable.Name).ToNot(Equal("HTTPS_PROXY"))
+				}
+			})
+		})
+		Context("Cluster Name", func() {
+			It("should qualify the name of new clusters with their namespace", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.AWSClusterName).To(Equal(controlPlane.QualifiedClusterName()))
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(etcdSet.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(object.ControlPlaneLabelKey, controlPlane.QualifiedClusterName()))
+			})
+			It("should keep the bare name of clusters provisioned before it was qualified", func() {
+				controlPlane.Spec.Master.CloudProvider = v1alpha1.CloudProviderExternal
+				ExpectCreated(kubeClient, controlPlane)
+				controlPlane.Status.Initialized = true
+				Expect(kubeClient.Status().Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.AWSClusterName).To(Equal(controlPlane.Name))
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(etcdSet.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(object.ControlPlaneLabelKey, controlPlane.Name))
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(apiServer.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(object.ControlPlaneLabelKey, controlPlane.Name))
+				kcm := ExpectDeploymentExists(kubeClient, master.KCMDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(kcm.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--cluster-name=kubernetes"))
+				ccm := ExpectDeploymentExists(kubeClient, master.CCMDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(ccm.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--cluster-name=" + controlPlane.Name))
+			})
+		})
+		Context("Network", func() {
+			It("should render the pod and service CIDRs into the component flags and certificate", func() {
+				controlPlane.Spec.Network = &v1alpha1.Network{PodCIDR: "192.168.0.0/16", ServiceCIDR: "172.20.0.0/16"}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(apiServer.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--service-cluster-ip-range=172.20.0.0/16"))
+				kcm := ExpectDeploymentExists(kubeClient, master.KCMDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				Expect(kcm.Spec.Template.Spec.Containers[0].Args).To(ContainElements(
+					"--allocate-node-cidrs=true",
+					"--cluster-cidr=192.168.0.0/16",
+					"--service-cluster-ip-range=172.20.0.0/16",
+				))
+				secret := ExpectSecretExists(kubeClient, master.KubeAPIServerSecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				certs, err := certutil.ParseCertsPEM(secret.Data[secrets.SecretPublicKey])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(certs[0].IPAddresses).To(ContainElement(net.ParseIP("172.20.0.1").To4()))
+			})
+		})
+		Context("Certificates", func() {
+			It("should reissue the apiserver certificate when cert SANs are added", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				secret := ExpectSecretExists(kubeClient, master.KubeAPIServerSecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(dnsNamesOf(secret)).ToNot(ContainElement("api.example.com"))
+
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				controlPlane.Spec.Master.CertSANs = []string{"api.example.com"}
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				secret = ExpectSecretExists(kubeClient, master.KubeAPIServerSecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(dnsNamesOf(secret)).To(ContainElements("api.example.com", "kubernetes.default"))
+			})
+		})
+		Context("Manifest Audit", func() {
+			It("should summarize what a spec edit changed in the components", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				hash := controlPlane.Status.Manifests["apiserver"].Hash
+				Expect(hash).ToNot(BeEmpty())
+				Expect(controlPlane.Status.Manifests["apiserver"].LastChange).To(BeEmpty())
+
+				controlPlane.Spec.Master.FeatureGates = map[string]bool{"EphemeralContainers": true}
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Manifests["apiserver"].Hash).ToNot(Equal(hash))
+				Expect(controlPlane.Status.Manifests["apiserver"].LastChange).To(ContainSubstring("args added --feature-gates=EphemeralContainers=true"))
+				Expect(controlPlane.Status.Manifests["apiserver"].LastChanged).ToNot(BeNil())
+				Expect(controlPlane.Status.Manifests["etcd"].LastChange).To(BeEmpty())
+			})
+		})
+		Context("History", func() {
+			It("should keep the actions of the operator on the cluster", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				controlPlane.Spec.Master.FeatureGates = map[string]bool{"EphemeralContainers": true}
+				Expect(kubeClient.Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcile(context.Background(), &controllers.GenericController{Controller: controller, Client: kubeClient}, client.ObjectKeyFromObject(controlPlane))
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				var actions []string
+				for _, entry := range controlPlane.Status.History {
+					actions = append(actions, entry.Reason+": "+entry.Message)
+				}
+				Expect(actions).To(ContainElements(
+					"Provisioned: ",
+					"ComponentCreated: apiserver created",
+					"ComponentCreated: etcd created",
+				))
+				Expect(controlPlane.Status.History[len(controlPlane.Status.History)-1].Reason).To(Equal("ManifestChanged"))
+			})
+		})
+		Context("Tag Tampering", func() {
+			It("should tag the load balancer and record an event when its tags were changed", func() {
+				detector := &fakeDetector{}
+				recorder := record.NewFakeRecorder(10)
+				guarded := &controllers.GenericController{Client: kubeClient, Controller: controlplane.NewController(kubeClient, controlplane.Options{
+					Guard:    detector,
+					Recorder: recorder,
+				})}
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcile(context.Background(), guarded, client.ObjectKeyFromObject(controlPlane))
+				service := &v1.Service{}
+				Expect(kubeClient.Get(context.Background(), object.NamespacedName(master.ServiceNameFor(controlPlane.ClusterName()), controlPlane.Namespace), service)).To(Succeed())
+				Expect(service.Annotations).To(HaveKeyWithValue("service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags",
+					fmt.Sprintf("kit.aws/cluster=%s/%s,kit.aws/managed=true", controlPlane.Namespace, controlPlane.Name)))
+
+				detector.tampered = []string{"kit.aws/managed removed"}
+				patchControlPlaneService(context.Background(), controlPlane)
+				ExpectReconcile(context.Background(), guarded, client.ObjectKeyFromObject(controlPlane))
+				Expect(recorder.Events).To(Receive(ContainSubstring("TagsTampered")))
+			})
+		})
+		Context("Bug Report", func() {
+			It("should bundle the control plane, its graph, events and logs", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				event := &v1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "etcd-event", Namespace: controlPlane.Namespace},
+					InvolvedObject: v1.ObjectReference{Kind: "StatefulSet", Name: etcd.ServiceNameFor(controlPlane.Name)},
+					Type:           v1.EventTypeWarning,
+					Reason:         "FailedCreate",
+					Message:        "exceeded quota",
+				}
+				unrelated := &v1.Event{
+					ObjectMeta:     metav1.ObjectMeta{Name: "other-event", Namespace: controlPlane.Namespace},
+					InvolvedObject: v1.ObjectReference{Kind: "Pod", Name: "other"},
+				}
+				handler := bugreport.NewHandler(kubeClient, fake.NewSimpleClientset(event, unrelated), types.NamespacedName{Namespace: "kit", Name: "kit-controller-abc12"})
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s%s/%s", bugreport.Path, controlPlane.Namespace, controlPlane.Name), nil))
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				gzipReader, err := gzip.NewReader(recorder.Body)
+				Expect(err).ToNot(HaveOccurred())
+				files := map[string]string{}
+				tarReader := tar.NewReader(gzipReader)
+				for header, err := tarReader.Next(); err != io.EOF; header, err = tarReader.Next() {
+					Expect(err).ToNot(HaveOccurred())
+					contents, err := ioutil.ReadAll(tarReader)
+					Expect(err).ToNot(HaveOccurred())
+					files[header.Name] = string(contents)
+				}
+				Expect(files).To(HaveKey(controlPlane.Name + "/logs/components.log"))
+				Expect(files).To(HaveKey(controlPlane.Name + "/logs/operator.log"))
+				Expect(files).To(HaveKeyWithValue(controlPlane.Name+"/controlplane.yaml", ContainSubstring("name: "+controlPlane.Name)))
+				Expect(files).To(HaveKeyWithValue(controlPlane.Name+"/graph.txt", ContainSubstring("StatefulSet/"+etcd.ServiceNameFor(controlPlane.Name))))
+				Expect(files).To(HaveKeyWithValue(controlPlane.Name+"/events.txt", ContainSubstring("exceeded quota")))
+				Expect(files[controlPlane.Name+"/events.txt"]).ToNot(ContainSubstring("pod/other"))
+			})
+		})
+		Context("Status", func() {
+			It("should estimate the hourly cost of the control plane", func() {
+				controlPlane.Spec.Master.Type = "m5.large"
+				controlPlane.Spec.Etcd.Type = "m5.large"
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.EstimatedHourlyCost).To(Equal("0.5985"))
+			})
+			It("should leave out the cost of control planes with unknown instance types", func() {
+				controlPlane.Spec.Master.Type = "m5.large"
+				controlPlane.Spec.Etcd.Type = "x9.large"
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.EstimatedHourlyCost).To(BeEmpty())
+			})
+			It("should report etcd as unhealthy when its members can't be reached", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Etcd).ToNot(BeNil())
+				Expect(controlPlane.Status.Etcd.Healthy).To(BeFalse())
+				Expect(controlPlane.Status.Etcd.Message).To(ContainSubstring("listing etcd members"))
+			})
+			It("should record how long each part took to become ready", func() {
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Provisioning).To(HaveKey(controlplane.ProvisioningEndpoint))
+				Expect(controlPlane.Status.Provisioning).ToNot(HaveKey(controlplane.ProvisioningControlPlane))
+
+				etcdSet := ExpectStatefulSetExists(kubeClient, etcd.ServiceNameFor(controlPlane.Name), controlPlane.Namespace)
+				etcdSet.Status.Replicas = *etcdSet.Spec.Replicas
+				etcdSet.Status.ReadyReplicas = *etcdSet.Spec.Replicas
+				Expect(kubeClient.Status().Update(context.Background(), etcdSet)).To(Succeed())
+				apiServer := ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+				apiServer.Status.Replicas = *apiServer.Spec.Replicas
+				apiServer.Status.ReadyReplicas = *apiServer.Spec.Replicas
+				Expect(kubeClient.Status().Update(context.Background(), apiServer)).To(Succeed())
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Provisioning).To(HaveKey(controlplane.ProvisioningEtcd))
+				Expect(controlPlane.Status.Provisioning).To(HaveKey(controlplane.ProvisioningAPIServer))
+				Expect(controlPlane.Status.Provisioning).To(HaveKey(controlplane.ProvisioningControlPlane))
+			})
+		})
+		Context("Cluster API", func() {
+			It("should report the status fields of the control plane provider contract", func() {
+				controlPlane.Spec.KubernetesVersion = "1.19"
+				ExpectCreated(kubeClient, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.Ready).To(BeTrue())
+				Expect(controlPlane.Status.Initialized).To(BeTrue())
+				Expect(controlPlane.Status.ExternalManagedControlPlane).To(BeTrue())
+				Expect(controlPlane.Status.Version).To(Equal("1.19"))
+				Expect(controlPlane.Status.Endpoint).To(Equal("https://elb-endpoint:443"))
+				secret := ExpectSecretExists(kubeClient, master.ClusterAPIKubeConfigSecretNameFor(controlPlane.Name), controlPlane.Namespace)
+				Expect(secret.Data).To(HaveKey("value"))
+			})
+		})
+		Context("Templates", func() {
+			It("should inherit fields from the template and apply overrides", func() {
+				template := &v1alpha1.ClusterTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "small", Namespace: controlPlane.Namespace},
+					Spec: v1alpha1.ClusterTemplateSpec{ControlPlane: v1alpha1.ControlPlaneSpec{
+						Master: v1alpha1.MasterSpec{Instances: v1alpha1.Instances{Type: "m5.large"}},
+						Etcd:   v1alpha1.ETCDSpec{Instances: v1alpha1.Instances{Type: "m5.large"}},
+					}},
+				}
+				controlPlane.Spec.Template = template.Name
+				controlPlane.Spec.Etcd.Type = "m5.xlarge"
+				ExpectCreated(kubeClient, template, controlPlane)
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(controlPlane), controlPlane)).To(Succeed())
+				Expect(controlPlane.Status.EstimatedHourlyCost).To(Equal("0.8865"))
+				Expect(controlPlane.Spec.Master.Type).To(BeEmpty())
+			})
+			It("should keep reconciling templated clusters running a version older than the default images", func() {
+				template := &v1alpha1.ClusterTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: controlPlane.Namespace},
+					Spec:       v1alpha1.ClusterTemplateSpec{ControlPlane: v1alpha1.ControlPlaneSpec{KubernetesVersion: "1.19"}},
+				}
+				controlPlane.Spec.Template = template.Name
+				ExpectCreated(kubeClient, template, controlPlane)
+				controlPlane.Status.Version = "1.19"
+				Expect(kubeClient.Status().Update(context.Background(), controlPlane)).To(Succeed())
+				ExpectReconcileWithInjectedService(context.Background(), controlPlane)
+				ExpectDeploymentExists(kubeClient, master.APIServerDeploymentName(controlPlane.Name), controlPlane.Namespace)
+			})
+		})
 	})
 })
 
+type fakePublisher struct {
+	events []notifications.Event
+}
+
+func (f *fakePublisher) Publish(_ context.Context, event notifications.Event) error {
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakePublisher) types() (types []notifications.EventType) {
+	for _, event := range f.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+type fakeChecker struct {
+	err    error
+	checks int
+}
+
+func (f *fakeChecker) Check(context.Context, *v1alpha1.ControlPlane) error {
+	f.checks++
+	return f.err
+}
+
+type fakeDetector struct {
+	tampered []string
+}
+
+func (f *fakeDetector) Tampered(context.Context, *v1alpha1.ControlPlane) ([]string, error) {
+	return f.tampered, nil
+}
+
+type fakeProber struct {
+	result *health.Result
+	err    error
+	probes int
+}
+
+func (f *fakeProber) Probe(context.Context, *v1alpha1.ControlPlane) (*health.Result, error) {
+	f.probes++
+	return f.result, f.err
+}
+
+func (f *fakeProber) Forget(*v1alpha1.ControlPlane) {}
+
+type fakeValidator struct {
+	err error
+}
+
+func (f *fakeValidator) Validate(context.Context, *v1alpha1.ControlPlane) error {
+	return f.err
+}
+
+func dnsNamesOf(secret *v1.Secret) []string {
+	certs, err := certutil.ParseCertsPEM(secret.Data[secrets.SecretPublicKey])
+	Expect(err).ToNot(HaveOccurred())
+	return certs[0].DNSNames
+}
+
 func ExpectReconcileWithInjectedService(ctx context.Context, controlPlane *v1alpha1.ControlPlane) {
 	genController := &controllers.GenericController{Controller: controller, Client: kubeClient}
 	ExpectReconcile(ctx, genController, client.ObjectKeyFromObject(controlPlane))
@@ -129,7 +1220,7 @@ func ExpectReconcileWithInjectedService(ctx context.Context, controlPlane *v1alp
 
 func patchControlPlaneService(ctx context.Context, controlPlane *v1alpha1.ControlPlane) {
 	svc := &v1.Service{}
-	Expect(kubeClient.Get(ctx, types.NamespacedName{controlPlane.Namespace, master.ServiceNameFor(controlPlane.Name)}, svc)).To(Succeed())
+	Expect(kubeClient.Get(ctx, types.NamespacedName{Namespace: controlPlane.Namespace, Name: master.ServiceNameFor(controlPlane.Name)}, svc)).To(Succeed())
 	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{Hostname: "elb-endpoint"}}
 	Expect(kubeClient.Status().Update(ctx, svc)).To(Succeed())
 }
@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controlplane
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+)
+
+// desiredStateFor returns a copy of the control plane with the referenced
+// ClusterTemplate applied to its spec. Fields set on the ControlPlane take
+// precedence over the template. The returned object is only used to render
+// the desired state, the spec stored in the API server is never changed.
+func (c *controlPlane) desiredStateFor(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (*v1alpha1.ControlPlane, error) {
+	desired, err := c.withTemplate(ctx, controlPlane)
+	if err != nil {
+		return nil, err
+	}
+	// Clusters already running the version aren't checked again, a release of
+	// KIT changing the default images shouldn't stop reconciling them
+	if controlPlane.Spec.Template != "" && desired.Spec.KubernetesVersion != controlPlane.Status.Version {
+		if errs := desired.Spec.ValidateCompatibility(); errs != nil {
+			return nil, fmt.Errorf("applying cluster template %s, %w", controlPlane.Spec.Template, errs)
+		}
+	}
+	c.withFederation(desired)
+	return desired, nil
+}
+
+// withTemplate returns a copy of the control plane with the referenced
+// ClusterTemplate applied to its spec
+func (c *controlPlane) withTemplate(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (*v1alpha1.ControlPlane, error) {
+	desired := controlPlane.DeepCopy()
+	if controlPlane.Spec.Template == "" {
+		return desired, nil
+	}
+	template := &v1alpha1.ClusterTemplate{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(controlPlane.Spec.Template, controlPlane.Namespace), template); err != nil {
+		return nil, fmt.Errorf("getting cluster template %s, %w", controlPlane.Spec.Template, err)
+	}
+	if err := desired.Spec.ApplyTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	return desired, nil
+}
@@ -16,12 +16,13 @@ package etcd
 
 import (
 	"context"
+	"sync"
 
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/kubeprovider"
 	"github.com/awslabs/kit/operator/pkg/utils/keypairs"
 
-	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
 )
 
 const (
@@ -31,6 +32,8 @@ const (
 type Controller struct {
 	kubeClient *kubeprovider.Client
 	keypairs   *keypairs.Provider
+	// httpClients has the client checking the health of each control plane
+	httpClients sync.Map
 }
 
 type reconciler func(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (err error)
@@ -43,12 +46,14 @@ func (c *Controller) Reconcile(ctx context.Context, controlPlane *v1alpha1.Contr
 	for _, reconcile := range []reconciler{
 		c.reconcileService,
 		c.reconcileSecrets,
+		c.reconcileStorageClass,
 		c.reconcileStatefulSet,
+		c.reconcileDisruptionBudget,
 	} {
 		if err := reconcile(ctx, controlPlane); err != nil {
 			return err
 		}
 	}
-	zap.S().Infof("[%v] etcd reconciled", controlPlane.ClusterName())
+	logging.FromContext(ctx).Info("etcd reconciled")
 	return nil
 }
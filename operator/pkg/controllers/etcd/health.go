@@ -0,0 +1,175 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const healthTimeout = 5 * time.Second
+
+// Health queries etcd through the JSON gateway on the client port for its
+// members, leader, DB size and alarms. A cluster which can't be reached is
+// reported as unhealthy with the error in the message. The previous status is
+// returned when nothing changed, so the ControlPlane isn't updated on every
+// check.
+func (c *Controller) Health(ctx context.Context, controlPlane *v1alpha1.ControlPlane) *v1alpha1.ETCDStatus {
+	status := &v1alpha1.ETCDStatus{}
+	if err := c.health(ctx, controlPlane, status); err != nil {
+		status.Healthy = false
+		status.Message = err.Error()
+	}
+	if previous := controlPlane.Status.Etcd; previous != nil {
+		status.LastTransitionTime = previous.LastTransitionTime
+		if equality.Semantic.DeepEqual(status, previous) {
+			return previous
+		}
+	}
+	status.LastTransitionTime = metav1.Now()
+	return status
+}
+
+func (c *Controller) health(ctx context.Context, controlPlane *v1alpha1.ControlPlane, status *v1alpha1.ETCDStatus) error {
+	httpClient, err := c.httpClientFor(ctx, controlPlane)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("https://%s:2379", serviceFQDN(controlPlane))
+	members := struct {
+		Members []struct {
+			ID         string   `json:"ID"`
+			Name       string   `json:"name"`
+			ClientURLs []string `json:"clientURLs"`
+		} `json:"members"`
+	}{}
+	if err := post(ctx, httpClient, endpoint+"/v3/cluster/member/list", struct{}{}, &members); err != nil {
+		return fmt.Errorf("listing etcd members, %w", err)
+	}
+	maintenance := struct {
+		Leader string `json:"leader"`
+		DBSize string `json:"dbSize"`
+	}{}
+	if err := post(ctx, httpClient, endpoint+"/v3/maintenance/status", struct{}{}, &maintenance); err != nil {
+		return fmt.Errorf("getting etcd status, %w", err)
+	}
+	alarms := struct {
+		Alarms []struct {
+			MemberID string `json:"memberID"`
+			Alarm    string `json:"alarm"`
+		} `json:"alarms"`
+	}{}
+	if err := post(ctx, httpClient, endpoint+"/v3/maintenance/alarm", map[string]string{"action": "GET"}, &alarms); err != nil {
+		return fmt.Errorf("getting etcd alarms, %w", err)
+	}
+	names := map[string]string{}
+	for _, member := range members.Members {
+		names[member.ID] = member.Name
+		status.Members = append(status.Members, v1alpha1.ETCDMember{ID: member.ID, Name: member.Name, ClientURLs: member.ClientURLs})
+	}
+	status.Leader = names[maintenance.Leader]
+	if status.DBSizeBytes, err = strconv.ParseInt(maintenance.DBSize, 10, 64); err != nil && maintenance.DBSize != "" {
+		return fmt.Errorf("parsing etcd db size %q, %w", maintenance.DBSize, err)
+	}
+	for _, alarm := range alarms.Alarms {
+		status.Alarms = append(status.Alarms, fmt.Sprintf("%s: %s", names[alarm.MemberID], alarm.Alarm))
+	}
+	status.Healthy = status.Leader != "" && len(status.Alarms) == 0
+	return nil
+}
+
+// httpClient is reused by the health checks of a control plane, keeping their
+// connections open, until its CA or client certificate changes
+type httpClient struct {
+	*http.Client
+	version string
+}
+
+func (c *Controller) httpClientFor(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (*http.Client, error) {
+	ca := &v1.Secret{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(CASecretNameFor(controlPlane.ClusterName()), controlPlane.Namespace), ca); err != nil {
+		return nil, fmt.Errorf("getting etcd CA, %w", err)
+	}
+	client := &v1.Secret{}
+	if err := c.kubeClient.Get(ctx, object.NamespacedName(EtcdAPIClientSecretNameFor(controlPlane.ClusterName()), controlPlane.Namespace), client); err != nil {
+		return nil, fmt.Errorf("getting etcd client certificate, %w", err)
+	}
+	key := object.NamespacedName(controlPlane.Name, controlPlane.Namespace)
+	version := ca.ResourceVersion + "/" + client.ResourceVersion
+	if cached, ok := c.httpClients.Load(key); ok {
+		if cached.(*httpClient).version == version {
+			return cached.(*httpClient).Client, nil
+		}
+		cached.(*httpClient).CloseIdleConnections()
+	}
+	privateKey, cert := secrets.Parse(client)
+	certificate, err := tls.X509KeyPair(cert, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing etcd client certificate, %w", err)
+	}
+	pool := x509.NewCertPool()
+	if _, caCert := secrets.Parse(ca); !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("parsing etcd CA")
+	}
+	cached := &httpClient{version: version, Client: &http.Client{Timeout: healthTimeout, Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}}}}
+	c.httpClients.Store(key, cached)
+	return cached.Client, nil
+}
+
+// Forget closes the connections of the health checks of a deleted control
+// plane
+func (c *Controller) Forget(controlPlane *v1alpha1.ControlPlane) {
+	if cached, ok := c.httpClients.LoadAndDelete(object.NamespacedName(controlPlane.Name, controlPlane.Namespace)); ok {
+		cached.(*httpClient).CloseIdleConnections()
+	}
+}
+
+func post(ctx context.Context, httpClient *http.Client, url string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -19,7 +19,10 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/config"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/patch"
 	"github.com/awslabs/kit/operator/pkg/utils/secrets"
@@ -29,15 +32,24 @@ import (
 
 const (
 	defaultEtcdReplicas = 3
-	defaultEtcdImage    = "public.ecr.aws/eks-distro/etcd-io/etcd:v3.4.14-eks-1-18-1"
 )
 
+func imageFor(controlPlane *v1alpha1.ControlPlane) string {
+	if controlPlane.Spec.Etcd.Image != "" {
+		return controlPlane.Spec.Etcd.Image
+	}
+	return images.Mirror(controlPlane.Spec.ImageRegistry, config.DefaultEtcdImage)
+}
+
 func podSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 	return &v1.PodSpec{
 		TerminationGracePeriodSeconds: aws.Int64(1),
 		HostNetwork:                   true,
 		DNSPolicy:                     v1.DNSClusterFirstWithHostNet,
-		NodeSelector:                  nodeSelector(controlPlane.ClusterName()),
+		PriorityClassName:             isolation.PriorityClassNameFor(controlPlane, ""),
+		NodeSelector:                  nodeSelector(controlPlane),
+		Tolerations:                   isolation.TolerationsFor(controlPlane),
+		ImagePullSecrets:              controlPlane.Spec.ImagePullSecrets,
 		TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
 			MaxSkew:           int32(1),
 			TopologyKey:       "topology.kubernetes.io/zone",
@@ -55,16 +67,18 @@ func podSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		}},
 		Containers: []v1.Container{{
 			Name:  "etcd",
-			Image: defaultEtcdImage,
+			Image: imageFor(controlPlane),
 			Ports: []v1.ContainerPort{{
 				ContainerPort: 2379,
 				Name:          "etcd",
+				Protocol:      v1.ProtocolTCP,
 			}, {
 				ContainerPort: 2380,
 				Name:          "etcd-peer",
+				Protocol:      v1.ProtocolTCP,
 			}},
 			VolumeMounts: []v1.VolumeMount{{
-				Name:      "etcd-data",
+				Name:      dataVolumeName,
 				MountPath: "/var/lib/etcd",
 			}, {
 				Name:      "etcd-ca",
@@ -115,7 +129,7 @@ func podSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 			}},
 		}},
 		Volumes: []v1.Volume{{
-			Name: "etcd-data",
+			Name: dataVolumeName,
 			VolumeSource: v1.VolumeSource{
 				HostPath: &v1.HostPathVolumeSource{
 					Path: "/var/lib/etcd",
@@ -201,7 +215,11 @@ func caPeerName(controlPlane *v1alpha1.ControlPlane) string {
 	return fmt.Sprintf("%s-etcd-peer", controlPlane.ClusterName())
 }
 
-func nodeSelector(clusterName string) map[string]string {
-	return patch.UnionStringMaps(labelsFor(clusterName),
-		map[string]string{object.ControlPlaneLabelKey: clusterName})
+func nodeSelector(controlPlane *v1alpha1.ControlPlane) map[string]string {
+	selector := patch.UnionStringMaps(labelsFor(controlPlane.ClusterName()),
+		map[string]string{object.ControlPlaneLabelKey: controlPlane.AWSClusterName()})
+	if architecture := controlPlane.Spec.Etcd.Architecture; architecture != "" {
+		selector[v1.LabelArchStable] = architecture
+	}
+	return isolation.NodeSelectorFor(controlPlane, selector)
 }
@@ -27,7 +27,7 @@ import (
 )
 
 func (c *Controller) reconcileService(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
-	return c.kubeClient.EnsureCreate(ctx, object.WithOwner(controlPlane, &v1.Service{
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ServiceNameFor(controlPlane.ClusterName()),
 			Namespace: controlPlane.Namespace,
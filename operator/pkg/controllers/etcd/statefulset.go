@@ -25,7 +25,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (c *Controller) reconcileStatefulSet(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
@@ -36,7 +38,7 @@ func (c *Controller) reconcileStatefulSet(ctx context.Context, controlPlane *v1a
 	if err != nil {
 		return fmt.Errorf("failed to patch pod spec, %w", err)
 	}
-	return c.kubeClient.EnsurePatch(ctx, &appsv1.StatefulSet{}, object.WithOwner(controlPlane, &appsv1.StatefulSet{
+	statefulSet := withStorage(controlPlane, &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ServiceNameFor(controlPlane.ClusterName()),
 			Namespace: controlPlane.Namespace,
@@ -54,5 +56,20 @@ func (c *Controller) reconcileStatefulSet(ctx context.Context, controlPlane *v1a
 				Spec: etcdSpec,
 			},
 		},
-	}))
+	})
+	// Volume claim templates can't be changed and are defaulted by the
+	// apiserver, keep the ones the StatefulSet was created with
+	existing := &appsv1.StatefulSet{}
+	if err := c.kubeClient.Get(ctx, client.ObjectKeyFromObject(statefulSet), existing); err == nil {
+		statefulSet.Spec.VolumeClaimTemplates = existing.Spec.VolumeClaimTemplates
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("getting statefulset, %w", err)
+	}
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, statefulSet))
+}
+
+// InstancesFor returns the number of etcd instances of the control plane, the
+// members are spread one per node.
+func InstancesFor(_ *v1alpha1.ControlPlane) int32 {
+	return defaultEtcdReplicas
 }
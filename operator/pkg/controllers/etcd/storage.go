@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	dataVolumeName     = "etcd-data"
+	defaultStorageSize = "20Gi"
+	defaultStorageType = "gp3"
+)
+
+// reconcileStorageClass creates a StorageClass for the volume parameters of
+// the cluster. StorageClasses are cluster scoped and immutable, so they are
+// named after their parameters and shared by all the clusters using them.
+func (c *Controller) reconcileStorageClass(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	storage := controlPlane.Spec.Etcd.Storage
+	if storage == nil {
+		return nil
+	}
+	parameters := map[string]string{"type": storageTypeFor(storage)}
+	if storage.IOPS != 0 {
+		parameters["iops"] = fmt.Sprint(storage.IOPS)
+	}
+	if storage.Throughput != 0 {
+		parameters["throughput"] = fmt.Sprint(storage.Throughput)
+	}
+	bindingMode := storagev1.VolumeBindingWaitForFirstConsumer
+	reclaimPolicy := v1.PersistentVolumeReclaimDelete
+	return c.kubeClient.EnsureCreate(ctx, &storagev1.StorageClass{
+		ObjectMeta:        metav1.ObjectMeta{Name: StorageClassNameFor(storage)},
+		Provisioner:       "ebs.csi.aws.com",
+		Parameters:        parameters,
+		VolumeBindingMode: &bindingMode,
+		ReclaimPolicy:     &reclaimPolicy,
+	})
+}
+
+// withStorage replaces the host path data volume with a volume claim for
+// every member
+func withStorage(controlPlane *v1alpha1.ControlPlane, statefulSet *appsv1.StatefulSet) *appsv1.StatefulSet {
+	storage := controlPlane.Spec.Etcd.Storage
+	if storage == nil {
+		return statefulSet
+	}
+	volumes := []v1.Volume{}
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name != dataVolumeName {
+			volumes = append(volumes, volume)
+		}
+	}
+	statefulSet.Spec.Template.Spec.Volumes = volumes
+	size := resource.MustParse(defaultStorageSize)
+	if storage.Size != nil {
+		size = *storage.Size
+	}
+	storageClassName := StorageClassNameFor(storage)
+	statefulSet.Spec.VolumeClaimTemplates = []v1.PersistentVolumeClaim{{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   dataVolumeName,
+			Labels: labelsFor(controlPlane.ClusterName()),
+		},
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			StorageClassName: &storageClassName,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: size},
+			},
+		},
+	}}
+	return statefulSet
+}
+
+// Finalize deletes the etcd volume claims, they aren't deleted along with the
+// StatefulSet
+func (c *Controller) Finalize(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	if err := c.kubeClient.DeleteAllOf(ctx, &v1.PersistentVolumeClaim{}, client.InNamespace(controlPlane.Namespace),
+		client.MatchingLabels(labelsFor(controlPlane.ClusterName()))); err != nil {
+		return fmt.Errorf("deleting etcd volume claims, %w", err)
+	}
+	return nil
+}
+
+func StorageClassNameFor(storage *v1alpha1.ETCDStorage) string {
+	name := []string{"kit-etcd", storageTypeFor(storage)}
+	if storage.IOPS != 0 {
+		name = append(name, fmt.Sprintf("%diops", storage.IOPS))
+	}
+	if storage.Throughput != 0 {
+		name = append(name, fmt.Sprintf("%dmibps", storage.Throughput))
+	}
+	return strings.Join(name, "-")
+}
+
+func storageTypeFor(storage *v1alpha1.ETCDStorage) string {
+	if storage.Type == "" {
+		return defaultStorageType
+	}
+	return storage.Type
+}
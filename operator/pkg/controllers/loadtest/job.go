@@ -0,0 +1,175 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultConfig       = "load"
+	defaultImage        = "197575167141.dkr.ecr.us-west-2.amazonaws.com/clusterloader2:76e3fd7"
+	perfTestsRepository = "https://github.com/kubernetes/perf-tests.git"
+	// defaultPerfTestsRef is the perf-tests commit the default image is built
+	// from, the test configs are read from the same commit
+	defaultPerfTestsRef = "76e3fd7"
+	overridesKey        = "overrides.yaml"
+	// UploadContainerName is the container of the Job uploading the reports
+	UploadContainerName = "upload-results"
+	// exitCodeFile is written by the clusterloader container when the test
+	// finishes, the upload container waits for it
+	exitCodeFile = "exit-code"
+
+	sourcePath     = "/src"
+	kubeConfigPath = "/etc/kubernetes/config"
+	overridesPath  = "/etc/clusterloader2"
+	resultsPath    = "/results"
+)
+
+// jobFor runs clusterloader2 next to a container uploading the reports once
+// it exits, so they are kept even when the test fails. The upload container
+// writes the summary of the junit report to its termination message.
+func jobFor(loadTest *v1alpha1.LoadTest, location string) client.Object {
+	config := loadTest.Spec.Config
+	if config == "" {
+		config = defaultConfig
+	}
+	image := loadTest.Spec.Image
+	if image == "" {
+		image = defaultImage
+	}
+	ref := loadTest.Spec.PerfTestsRef
+	if ref == "" {
+		ref = defaultPerfTestsRef
+	}
+	args := []string{
+		fmt.Sprintf("--kubeconfig=%s/%s", kubeConfigPath, secrets.SecretConfigKey),
+		fmt.Sprintf("--testconfig=%s/perf-tests/clusterloader2/testing/%s/config.yaml", sourcePath, config),
+		fmt.Sprintf("--testoverrides=%s/%s", overridesPath, overridesKey),
+		fmt.Sprintf("--nodes=%d", loadTest.Spec.Nodes),
+		"--provider=eks",
+		fmt.Sprintf("--report-dir=%s", resultsPath),
+		"--alsologtostderr",
+	}
+	upload := fmt.Sprintf("ls %s", resultsPath)
+	if location != "" {
+		upload = fmt.Sprintf("aws s3 cp %s %s --recursive", resultsPath, location)
+	}
+	return object.WithOwner(loadTest, &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      JobNameFor(loadTest.Name),
+			Namespace: loadTest.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: aws.Int32(0),
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					InitContainers: []v1.Container{{
+						Name:         "git-clone",
+						Image:        "alpine/git",
+						WorkingDir:   sourcePath,
+						Command:      []string{"sh", "-c"},
+						Args:         []string{fmt.Sprintf("git clone %s perf-tests && git -C perf-tests checkout %s", perfTestsRepository, ref)},
+						VolumeMounts: []v1.VolumeMount{{Name: "source", MountPath: sourcePath}},
+					}},
+					Containers: []v1.Container{{
+						Name:    "clusterloader",
+						Image:   image,
+						Command: []string{"sh", "-c", fmt.Sprintf(`/clusterloader "$@"; code=$?; echo ${code} > %s/%s; exit ${code}`, resultsPath, exitCodeFile), "clusterloader"},
+						Args:    args,
+						Env:     []v1.EnvVar{{Name: "ENABLE_EXEC_SERVICE", Value: "false"}},
+						VolumeMounts: []v1.VolumeMount{
+							{Name: "source", MountPath: sourcePath},
+							{Name: "kubeconfig", MountPath: kubeConfigPath, ReadOnly: true},
+							{Name: "overrides", MountPath: overridesPath, ReadOnly: true},
+							{Name: "results", MountPath: resultsPath},
+						},
+					}, {
+						Name:    UploadContainerName,
+						Image:   "amazon/aws-cli",
+						Command: []string{"sh", "-c"},
+						Args: []string{fmt.Sprintf(`until [ -f %[1]s/%[2]s ]; do sleep 10; done
+%[3]s || exit 1
+if [ -f %[1]s/junit.xml ]; then
+  tests=$(grep -o '<testcase' %[1]s/junit.xml | wc -l)
+  failures=$(grep -o '<failure' %[1]s/junit.xml | wc -l)
+  echo "{\"tests\": ${tests}, \"failures\": ${failures}}" > /dev/termination-log
+fi`, resultsPath, exitCodeFile, upload)},
+						VolumeMounts: []v1.VolumeMount{{Name: "results", MountPath: resultsPath}},
+					}},
+					Volumes: []v1.Volume{{
+						Name:         "source",
+						VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+					}, {
+						Name:         "results",
+						VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+					}, {
+						Name: "kubeconfig",
+						VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+							SecretName: master.KubeAdminSecretNameFor(loadTest.Spec.ControlPlane),
+						}},
+					}, {
+						Name: "overrides",
+						VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: OverridesNameFor(loadTest.Name)},
+						}},
+					}},
+				},
+			},
+		},
+	})
+}
+
+// overridesFor renders the clusterloader2 test overrides, the keys are sorted
+// so that the config map is the same on every reconcile
+func overridesFor(loadTest *v1alpha1.LoadTest) client.Object {
+	keys := make([]string, 0, len(loadTest.Spec.Overrides))
+	for key := range loadTest.Spec.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	overrides := strings.Builder{}
+	for _, key := range keys {
+		overrides.WriteString(fmt.Sprintf("%s: %s\n", key, loadTest.Spec.Overrides[key]))
+	}
+	return object.WithOwner(loadTest, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OverridesNameFor(loadTest.Name),
+			Namespace: loadTest.Namespace,
+		},
+		Data: map[string]string{overridesKey: overrides.String()},
+	})
+}
+
+func JobNameFor(loadTestName string) string {
+	return fmt.Sprintf("%s-clusterloader", loadTestName)
+}
+
+func OverridesNameFor(loadTestName string) string {
+	return fmt.Sprintf("%s-clusterloader-overrides", loadTestName)
+}
@@ -0,0 +1,173 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/kubeprovider"
+	"github.com/awslabs/kit/operator/pkg/results"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type loadTest struct {
+	kubeClient *kubeprovider.Client
+	// clientSet lists the pods of the Jobs without caching every pod
+	clientSet kubernetes.Interface
+}
+
+// NewController returns a controller for running clusterloader2 against KIT
+// clusters
+func NewController(kubeClient client.Client, clientSet kubernetes.Interface) *loadTest {
+	return &loadTest{kubeClient: kubeprovider.New(kubeClient), clientSet: clientSet}
+}
+
+// Name returns the name of the controller
+func (l *loadTest) Name() string {
+	return "load-test"
+}
+
+// For returns the resource this controller is for.
+func (l *loadTest) For() controllers.Object {
+	return &v1alpha1.LoadTest{}
+}
+
+// Owns returns the types of the objects running a LoadTest
+func (l *loadTest) Owns() []client.Object {
+	return []client.Object{&batchv1.Job{}, &v1.ConfigMap{}}
+}
+
+// Reconcile starts a clusterloader2 Job once the kubeconfig for the target
+// cluster exists and syncs the Job's progress to LoadTest.Status
+func (l *loadTest) Reconcile(ctx context.Context, object controllers.Object) (*reconcile.Result, error) {
+	loadTest := object.(*v1alpha1.LoadTest)
+	if err := l.kubeClient.Get(ctx, objectKey(master.KubeAdminSecretNameFor(loadTest.Spec.ControlPlane), loadTest.Namespace), &v1.Secret{}); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("getting kubeconfig for control plane %s, %w", loadTest.Spec.ControlPlane, errors.WaitingForSubResources)
+		}
+		return nil, fmt.Errorf("getting kubeconfig for control plane %s, %w", loadTest.Spec.ControlPlane, err)
+	}
+	if err := l.kubeClient.EnsureCreate(ctx, overridesFor(loadTest)); err != nil {
+		return nil, fmt.Errorf("ensuring test overrides, %w", err)
+	}
+	location, err := l.resultsFor(ctx, loadTest)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.kubeClient.EnsureCreate(ctx, jobFor(loadTest, location)); err != nil {
+		return nil, fmt.Errorf("ensuring job, %w", err)
+	}
+	job := &batchv1.Job{}
+	if err := l.kubeClient.Get(ctx, objectKey(JobNameFor(loadTest.Name), loadTest.Namespace), job); err != nil {
+		return nil, fmt.Errorf("getting job, %w", err)
+	}
+	syncStatus(loadTest, job, location)
+	if loadTest.Status.Phase == v1alpha1.LoadTestRunning {
+		return results.Waiting, nil
+	}
+	if loadTest.Status.Summary == nil {
+		summary, err := l.summaryFor(ctx, job)
+		if err != nil {
+			return nil, err
+		}
+		loadTest.Status.Summary = summary
+	}
+	return results.Terminated, nil
+}
+
+// summaryFor reads the summary the upload container of the Job wrote to its
+// termination message, or returns nil when there's none
+func (l *loadTest) summaryFor(ctx context.Context, job *batchv1.Job) (*v1alpha1.LoadTestSummary, error) {
+	pods, err := l.clientSet.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{"job-name": job.Name}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods of job %s, %w", job.Name, err)
+	}
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != UploadContainerName || status.State.Terminated == nil || status.State.Terminated.Message == "" {
+				continue
+			}
+			summary := &v1alpha1.LoadTestSummary{}
+			if err := json.Unmarshal([]byte(status.State.Terminated.Message), summary); err != nil {
+				return nil, fmt.Errorf("parsing summary of pod %s, %w", pod.Name, err)
+			}
+			return summary, nil
+		}
+	}
+	return nil, nil
+}
+
+// resultsFor returns the S3 URL the reports are uploaded to, the results
+// bucket of the LoadTest takes precedence over the artifact bucket of its
+// ControlPlane. There are no results when neither is set.
+func (l *loadTest) resultsFor(ctx context.Context, loadTest *v1alpha1.LoadTest) (string, error) {
+	if loadTest.Spec.ResultsBucket != "" {
+		return fmt.Sprintf("s3://%s/%s", loadTest.Spec.ResultsBucket, loadTest.Name), nil
+	}
+	controlPlane := &v1alpha1.ControlPlane{}
+	if err := l.kubeClient.Get(ctx, objectKey(loadTest.Spec.ControlPlane, loadTest.Namespace), controlPlane); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("getting control plane %s, %w", loadTest.Spec.ControlPlane, err)
+	}
+	if controlPlane.Status.Artifacts == "" {
+		return "", nil
+	}
+	return fmt.Sprintf("%s/loadtests/%s", controlPlane.Status.Artifacts, loadTest.Name), nil
+}
+
+func (l *loadTest) Finalize(_ context.Context, _ controllers.Object) (*reconcile.Result, error) {
+	return results.Terminated, nil
+}
+
+func syncStatus(loadTest *v1alpha1.LoadTest, job *batchv1.Job, location string) {
+	loadTest.Status.Phase = v1alpha1.LoadTestRunning
+	loadTest.Status.StartTime = job.Status.StartTime
+	loadTest.Status.CompletionTime = job.Status.CompletionTime
+	for i, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			loadTest.Status.Phase = v1alpha1.LoadTestSucceeded
+		case batchv1.JobFailed:
+			// Jobs only set the completion time when they succeed
+			loadTest.Status.Phase = v1alpha1.LoadTestFailed
+			loadTest.Status.CompletionTime = &job.Status.Conditions[i].LastTransitionTime
+		}
+	}
+	loadTest.Status.Results = location
+}
+
+func objectKey(name, namespace string) client.ObjectKey {
+	return object.NamespacedName(name, namespace)
+}
@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers"
+	"github.com/awslabs/kit/operator/pkg/controllers/loadtest"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/test/environment"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	. "github.com/awslabs/kit/operator/pkg/test/expectations"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+var (
+	controller controllers.Controller
+	kubeClient client.Client
+	env        *environment.Environment
+	scheme     = runtime.NewScheme()
+)
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+}
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LoadTest")
+}
+
+var _ = BeforeSuite(func() {
+	env = environment.New()
+	Expect(env.Start(scheme)).To(Succeed(), "Failed to start environment")
+	kubeClient = env.Client
+	controller = loadtest.NewController(kubeClient, kubernetes.NewForConfigOrDie(env.Config))
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = Describe("LoadTest", func() {
+	var loadTest *v1alpha1.LoadTest
+	BeforeEach(func() {
+		loadTest = &v1alpha1.LoadTest{
+			ObjectMeta: metav1.ObjectMeta{Name: "testload", Namespace: "default"},
+			Spec: v1alpha1.LoadTestSpec{
+				ControlPlane:  "testcluster",
+				Nodes:         10,
+				Overrides:     map[string]string{"PODS_PER_NODE": "30"},
+				ResultsBucket: "results/kit",
+			},
+		}
+	})
+	AfterEach(func() {
+		ExpectCleanedUp(kubeClient)
+		jobs := &batchv1.JobList{}
+		Expect(kubeClient.List(context.Background(), jobs)).To(Succeed())
+		for i := range jobs.Items {
+			// Jobs orphan their pods by default, which leaves a finalizer
+			// nothing in this environment removes
+			Expect(kubeClient.Delete(context.Background(), &jobs.Items[i], client.PropagationPolicy(metav1.DeletePropagationBackground))).To(Succeed())
+			ExpectNotFound(kubeClient, &jobs.Items[i])
+		}
+	})
+	Context("Reconcilation", func() {
+		It("should wait for the control plane kubeconfig", func() {
+			ExpectCreated(kubeClient, loadTest)
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(loadTest))
+			ExpectNotFound(kubeClient, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: loadtest.JobNameFor(loadTest.Name), Namespace: loadTest.Namespace}})
+		})
+		It("should run clusterloader2 and report the outcome", func() {
+			ExpectCreated(kubeClient, kubeConfigFor(loadTest), loadTest)
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(loadTest))
+			job := &batchv1.Job{}
+			Expect(kubeClient.Get(context.Background(), client.ObjectKey{Name: loadtest.JobNameFor(loadTest.Name), Namespace: loadTest.Namespace}, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.InitContainers[0].Args[0]).To(HaveSuffix("checkout 76e3fd7"))
+			Expect(job.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--testconfig=/src/perf-tests/clusterloader2/testing/load/config.yaml"))
+			Expect(job.Spec.Template.Spec.Containers[1].Args[0]).To(ContainSubstring("aws s3 cp /results s3://results/kit/testload --recursive"))
+			overrides := &v1.ConfigMap{}
+			Expect(kubeClient.Get(context.Background(), client.ObjectKey{Name: loadtest.OverridesNameFor(loadTest.Name), Namespace: loadTest.Namespace}, overrides)).To(Succeed())
+			Expect(overrides.Data).To(HaveKeyWithValue("overrides.yaml", "PODS_PER_NODE: 30\n"))
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(loadTest), loadTest)).To(Succeed())
+			Expect(loadTest.Status.Phase).To(Equal(v1alpha1.LoadTestRunning))
+
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "testload-clusterloader-abcde", Namespace: loadTest.Namespace, Labels: map[string]string{"job-name": job.Name}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: loadtest.UploadContainerName, Image: "amazon/aws-cli"}}},
+			}
+			ExpectCreated(kubeClient, pod)
+			pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+				Name:  loadtest.UploadContainerName,
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Message: `{"tests": 12, "failures": 1}`}},
+			}}
+			Expect(kubeClient.Status().Update(context.Background(), pod)).To(Succeed())
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+			Expect(kubeClient.Status().Update(context.Background(), job)).To(Succeed())
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(loadTest))
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(loadTest), loadTest)).To(Succeed())
+			Expect(loadTest.Status.Phase).To(Equal(v1alpha1.LoadTestSucceeded))
+			Expect(loadTest.Status.Results).To(Equal("s3://results/kit/testload"))
+			Expect(loadTest.Status.Summary).To(Equal(&v1alpha1.LoadTestSummary{Tests: 12, Failures: 1}))
+			Expect(kubeClient.Delete(context.Background(), pod)).To(Succeed())
+		})
+		It("should upload to the artifact bucket of the control plane without a results bucket", func() {
+			loadTest.Spec.ResultsBucket = ""
+			controlPlane := &v1alpha1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: loadTest.Spec.ControlPlane, Namespace: loadTest.Namespace}}
+			ExpectCreated(kubeClient, controlPlane, kubeConfigFor(loadTest), loadTest)
+			controlPlane.Status.Artifacts = "s3://artifacts/default/testcluster"
+			Expect(kubeClient.Status().Update(context.Background(), controlPlane)).To(Succeed())
+			ExpectReconcile(context.Background(), genericController(), client.ObjectKeyFromObject(loadTest))
+			job := &batchv1.Job{}
+			Expect(kubeClient.Get(context.Background(), client.ObjectKey{Name: loadtest.JobNameFor(loadTest.Name), Namespace: loadTest.Namespace}, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Containers[1].Args[0]).To(ContainSubstring("s3://artifacts/default/testcluster/loadtests/testload"))
+			Expect(kubeClient.Get(context.Background(), client.ObjectKeyFromObject(loadTest), loadTest)).To(Succeed())
+			Expect(loadTest.Status.Results).To(Equal("s3://artifacts/default/testcluster/loadtests/testload"))
+		})
+	})
+})
+
+func genericController() *controllers.GenericController {
+	return &controllers.GenericController{Controller: controller, Client: kubeClient}
+}
+
+func kubeConfigFor(loadTest *v1alpha1.LoadTest) *v1.Secret {
+	return &v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      master.KubeAdminSecretNameFor(loadTest.Spec.ControlPlane),
+		Namespace: loadTest.Namespace,
+	}}
+}
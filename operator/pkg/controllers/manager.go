@@ -51,8 +51,13 @@ func (m *GenericControllerManager) RegisterControllers(controllers ...Controller
 				&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
 			),
 		})
+		if owner, ok := c.(Owner); ok {
+			for _, owned := range owner.Owns() {
+				builder = builder.Owns(owned)
+			}
+		}
 		builder.Named(c.Name())
-		if err := builder.Complete(&GenericController{Controller: c, Client: m.GetClient()}); err != nil {
+		if err := builder.Complete(&GenericController{Controller: c, Client: m.GetClient(), Recorder: m.GetEventRecorderFor(c.Name())}); err != nil {
 			panic(fmt.Sprintf("Failed to register controller to manager for %s", controlledObject))
 		}
 		if err := controllerruntime.NewWebhookManagedBy(m).For(controlledObject).Complete(); err != nil {
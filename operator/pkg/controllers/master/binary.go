@@ -0,0 +1,63 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	binaryDownloadImage = "curlimages/curl:7.78.0"
+	binaryVolumeName    = "binaries"
+	binaryPath          = "/opt/kit/bin"
+)
+
+// withBinaryFrom runs the component container from a binary downloaded from
+// component.BinaryURL instead of the one shipped in the image. An init
+// container fetches the binary into a volume shared with the component
+// container, the image still provides the rest of the filesystem.
+func withBinaryFrom(controlPlane *v1alpha1.ControlPlane, component *v1alpha1.Component, spec v1.PodSpec) v1.PodSpec {
+	if component == nil || component.BinaryURL == "" {
+		return spec
+	}
+	container := &spec.Containers[0]
+	binary := path.Join(binaryPath, container.Command[0])
+	spec.InitContainers = append(spec.InitContainers, v1.Container{
+		Name:    fmt.Sprintf("download-%s", container.Name),
+		Image:   images.Mirror(controlPlane.Spec.ImageRegistry, binaryDownloadImage),
+		Command: []string{"sh", "-c"},
+		Args:    []string{fmt.Sprintf("curl -sSfL -o %[1]s %[2]s && chmod +x %[1]s", binary, component.BinaryURL)},
+		VolumeMounts: []v1.VolumeMount{{
+			Name:      binaryVolumeName,
+			MountPath: binaryPath,
+		}},
+	})
+	container.Command = []string{binary}
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      binaryVolumeName,
+		MountPath: binaryPath,
+		ReadOnly:  true,
+	})
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name:         binaryVolumeName,
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	})
+	return spec
+}
@@ -43,11 +43,15 @@ func (c *Controller) reconcileCertificates(ctx context.Context, cp *v1alpha1.Con
 	if err != nil {
 		return err
 	}
+	serviceIP, err := apiServerVirtualIP(cp.ServiceCIDR())
+	if err != nil {
+		return err
+	}
 	controlPlaneCA := rootCACertConfig(object.NamespacedName(cp.ClusterName(), cp.Namespace))
 	frontProxyCA := frontProxyCACertConfig(object.NamespacedName(cp.ClusterName(), cp.Namespace))
 	certsTreeMap := keypairs.CertTree{
 		controlPlaneCA: {
-			kubeAPIServerCertConfig(endpoint, nn),
+			kubeAPIServerCertConfig(endpoint, cp.Spec.Master.CertSANs, serviceIP, nn),
 			kubeletClientCertConfig(nn),
 		},
 		frontProxyCA: {
@@ -68,7 +72,7 @@ func rootCACertConfig(nn types.NamespacedName) *secrets.Request {
 	}
 }
 
-func kubeAPIServerCertConfig(hostname string, nn types.NamespacedName) *secrets.Request {
+func kubeAPIServerCertConfig(hostname string, certSANs []string, serviceIP net.IP, nn types.NamespacedName) *secrets.Request {
 	return &secrets.Request{
 		Name:      KubeAPIServerSecretNameFor(nn.Name),
 		Namespace: nn.Namespace,
@@ -77,9 +81,9 @@ func kubeAPIServerCertConfig(hostname string, nn types.NamespacedName) *secrets.
 			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 			CommonName: "kube-apiserver",
 			AltNames: certutil.AltNames{
-				DNSNames: []string{hostname, "localhost", "kubernetes", "kubernetes.default",
-					"kubernetes.default.svc", "kubernetes.default.svc.cluster.local"},
-				IPs: []net.IP{net.IPv4(127, 0, 0, 1), apiServerVirtualIP()},
+				DNSNames: append([]string{hostname, "localhost", "kubernetes", "kubernetes.default",
+					"kubernetes.default.svc", "kubernetes.default.svc.cluster.local"}, certSANs...),
+				IPs: []net.IP{net.IPv4(127, 0, 0, 1), serviceIP},
 			},
 		},
 	}
@@ -143,7 +147,19 @@ func FrontProxyCASecretNameFor(clusterName string) string {
 	return fmt.Sprintf("%s-front-proxy-ca", clusterName)
 }
 
-// TODO get this from controlPlane object
-func apiServerVirtualIP() net.IP {
-	return net.IPv4(10, 96, 0, 1)
+// apiServerVirtualIP returns the cluster IP of the kubernetes service, the
+// first address of the service CIDR
+func apiServerVirtualIP(serviceCIDR string) (net.IP, error) {
+	_, ipNet, err := net.ParseCIDR(serviceCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("parsing service CIDR, %w", err)
+	}
+	ip := make(net.IP, len(ipNet.IP))
+	copy(ip, ipNet.IP)
+	for i := len(ip) - 1; i >= 0; i-- {
+		if ip[i]++; ip[i] != 0 {
+			break
+		}
+	}
+	return ip, nil
 }
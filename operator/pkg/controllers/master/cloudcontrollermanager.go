@@ -0,0 +1,179 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	cloudControllerManagerImage = "k8s.gcr.io/provider-aws/cloud-controller-manager:v1.20.0-alpha.0"
+)
+
+// reconcileCCM runs the AWS cloud controller manager next to KCM when the
+// cluster uses an external cloud provider, and removes it otherwise.
+func (c *Controller) reconcileCCM(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	deployment := object.WithOwner(controlPlane, ccmDeploymentSpec(controlPlane))
+	if controlPlane.Spec.Master.CloudProvider != v1alpha1.CloudProviderExternal {
+		if err := c.kubeClient.Delete(ctx, deployment); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("deleting cloud controller manager, %w", err)
+		}
+		return nil
+	}
+	return c.kubeClient.EnsureApply(ctx, deployment)
+}
+
+// withCloudProvider sets --cloud-provider on the component container when the
+// cluster uses one of the cloud providers the component is configured for
+func withCloudProvider(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec, cloudProviders ...v1alpha1.CloudProvider) v1.PodSpec {
+	for _, cloudProvider := range cloudProviders {
+		if controlPlane.Spec.Master.CloudProvider == cloudProvider {
+			spec.Containers[0].Args = append(spec.Containers[0].Args, fmt.Sprintf("--cloud-provider=%s", cloudProvider))
+		}
+	}
+	return spec
+}
+
+func ccmDeploymentSpec(controlPlane *v1alpha1.ControlPlane) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CCMDeploymentName(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: ccmLabels(controlPlane.ClusterName()),
+			},
+			Replicas: replicasFor(controlPlane),
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: ccmLabels(controlPlane.ClusterName()),
+				},
+				Spec: WithProxy(controlPlane, ccmPodSpecFor(controlPlane)),
+			},
+		},
+	}
+}
+
+func CCMDeploymentName(clusterName string) string {
+	return fmt.Sprintf("%s-cloud-controller-manager", clusterName)
+}
+
+func ccmLabels(clusterName string) map[string]string {
+	return map[string]string{
+		object.AppNameLabelKey: CCMDeploymentName(clusterName),
+	}
+}
+
+func ccmPodSpecFor(controlPlane *v1alpha1.ControlPlane) v1.PodSpec {
+	return v1.PodSpec{
+		TerminationGracePeriodSeconds: aws.Int64(1),
+		HostNetwork:                   true,
+		DNSPolicy:                     v1.DNSClusterFirstWithHostNet,
+		PriorityClassName:             isolation.PriorityClassNameFor(controlPlane, "system-node-critical"),
+		NodeSelector:                  nodeSelector(controlPlane),
+		Tolerations:                   isolation.TolerationsFor(controlPlane),
+		ImagePullSecrets:              controlPlane.Spec.ImagePullSecrets,
+		TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
+			MaxSkew:           int32(1),
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: v1.DoNotSchedule,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: ccmLabels(controlPlane.ClusterName()),
+			},
+		}},
+		Affinity: &v1.Affinity{PodAffinity: &v1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{
+				LabelSelector: &metav1.LabelSelector{MatchLabels: apiServerLabels(controlPlane.ClusterName())},
+				TopologyKey:   "kubernetes.io/hostname",
+			}},
+		}},
+		Containers: []v1.Container{{
+			Name:    "cloud-controller-manager",
+			Image:   images.Mirror(controlPlane.Spec.ImageRegistry, cloudControllerManagerImage),
+			Command: []string{"/bin/aws-cloud-controller-manager"},
+			Resources: v1.ResourceRequirements{
+				Requests: map[v1.ResourceName]resource.Quantity{
+					v1.ResourceCPU: resource.MustParse("200m"),
+				},
+			},
+			Args: []string{
+				"--authentication-kubeconfig=/etc/kubernetes/config/ccm/cloud-controller-manager.conf",
+				"--authorization-kubeconfig=/etc/kubernetes/config/ccm/cloud-controller-manager.conf",
+				"--bind-address=127.0.0.1",
+				"--cloud-provider=aws",
+				"--cluster-name=" + controlPlane.AWSClusterName(),
+				"--configure-cloud-routes=false",
+				"--kubeconfig=/etc/kubernetes/config/ccm/cloud-controller-manager.conf",
+				"--leader-elect=true",
+				"--secure-port=10268",
+			},
+			VolumeMounts: []v1.VolumeMount{{
+				Name:      "ccm-config",
+				MountPath: "/etc/kubernetes/config/ccm",
+				ReadOnly:  true,
+			}},
+		}},
+		Volumes: []v1.Volume{{
+			Name: "ccm-config",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  KubeCloudControllerManagerSecretNameFor(controlPlane.ClusterName()),
+					DefaultMode: aws.Int32(0400),
+					Items: []v1.KeyToPath{{
+						Key:  "config",
+						Path: "cloud-controller-manager.conf",
+					}},
+				},
+			},
+		}},
+	}
+}
+
+// CCM authenticates as the system:cloud-controller-manager user, the addons
+// bind it to the upstream roles of the AWS cloud controller manager
+func kubeCloudControllerManagerCertConfig(clusterName, endpoint string, caSecret *v1.Secret) *configRequest {
+	return &configRequest{
+		endpoint: endpoint,
+		auth: &secrets.Request{
+			Name:     KubeCloudControllerManagerSecretNameFor(clusterName),
+			Type:     secrets.KeyWithSignedCert,
+			CASecret: caSecret,
+			Config: &certutil.Config{
+				Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+				CommonName: "system:cloud-controller-manager",
+			},
+		},
+	}
+}
+
+func KubeCloudControllerManagerSecretNameFor(clusterName string) string {
+	return fmt.Sprintf("%s-cloud-controller-manager-config", clusterName)
+}
@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Cluster API looks up the kubeconfig for a workload cluster in a secret
+	// of this type, labeled with the cluster name and storing the kubeconfig
+	// under the value key.
+	clusterAPISecretType     = "cluster.x-k8s.io/secret"
+	clusterAPIClusterNameKey = "cluster.x-k8s.io/cluster-name"
+	clusterAPIKubeConfigKey  = "value"
+)
+
+// reconcileClusterAPIKubeConfig publishes the admin kubeconfig in the format
+// Cluster API expects from a control plane provider, so that CAPI can reach
+// the cluster when a Cluster object references this ControlPlane.
+func (c *Controller) reconcileClusterAPIKubeConfig(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	admin, err := c.keypairs.GetSecretFromServer(ctx,
+		object.NamespacedName(KubeAdminSecretNameFor(controlPlane.ClusterName()), controlPlane.Namespace))
+	if err != nil {
+		return err
+	}
+	// Applied rather than created so the secret follows the admin kubeconfig
+	// when it changes
+	if err := c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterAPIKubeConfigSecretNameFor(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+			Labels:    map[string]string{clusterAPIClusterNameKey: controlPlane.ClusterName()},
+		},
+		Type: clusterAPISecretType,
+		Data: map[string][]byte{clusterAPIKubeConfigKey: admin.Data[secrets.SecretConfigKey]},
+	})); err != nil {
+		return fmt.Errorf("ensuring cluster api kube config, %w", err)
+	}
+	return nil
+}
+
+func ClusterAPIKubeConfigSecretNameFor(clusterName string) string {
+	return fmt.Sprintf("%s-kubeconfig", clusterName)
+}
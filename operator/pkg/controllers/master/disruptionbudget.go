@@ -0,0 +1,43 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+)
+
+// reconcileDisruptionBudgets creates a PodDisruptionBudget for each of the
+// master components of an isolated control plane
+func (c *Controller) reconcileDisruptionBudgets(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	if controlPlane.Spec.Isolation == nil {
+		return nil
+	}
+	for name, labels := range map[string]map[string]string{
+		APIServerDeploymentName(controlPlane.ClusterName()): apiServerLabels(controlPlane.ClusterName()),
+		KCMDeploymentName(controlPlane.ClusterName()):       kcmLabels(controlPlane.ClusterName()),
+		SchedulerDeploymentName(controlPlane.ClusterName()): schedulerLabels(controlPlane.ClusterName()),
+	} {
+		if err := c.kubeClient.EnsureCreate(ctx, object.WithOwner(controlPlane,
+			isolation.PodDisruptionBudgetFor(controlPlane, name, labels))); err != nil {
+			return fmt.Errorf("ensuring pod disruption budget %s, %w", name, err)
+		}
+	}
+	return nil
+}
@@ -17,9 +17,12 @@ package master
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/guard"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -28,32 +31,51 @@ import (
 )
 
 func (c *Controller) reconcileEndpoint(ctx context.Context, cp *v1alpha1.ControlPlane) (err error) {
-	return c.kubeClient.EnsureCreate(ctx, object.WithOwner(cp, &v1.Service{
+	ports := []v1.ServicePort{{
+		Port:       443,
+		Name:       apiserverPortName(cp.ClusterName()),
+		TargetPort: intstr.IntOrString{Type: intstr.Int, IntVal: 443},
+		Protocol:   "TCP",
+	}}
+	if KonnectivityEnabled(cp) {
+		ports = append(ports, v1.ServicePort{
+			Port:       KonnectivityAgentPort,
+			Name:       "konnectivity",
+			TargetPort: intstr.FromInt(KonnectivityAgentPort),
+			Protocol:   "TCP",
+		})
+	}
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(cp, &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      ServiceNameFor(cp.ClusterName()),
 			Namespace: cp.Namespace,
 			Annotations: map[string]string{
-				"service.beta.kubernetes.io/aws-load-balancer-scheme":                  "internet-facing",
-				"service.beta.kubernetes.io/aws-load-balancer-type":                    "nlb-ip",
-				"service.beta.kubernetes.io/aws-load-balancer-target-group-attributes": "stickiness.enabled=true,stickiness.type=source_ip",
+				"service.beta.kubernetes.io/aws-load-balancer-scheme":                   "internet-facing",
+				"service.beta.kubernetes.io/aws-load-balancer-type":                     "nlb-ip",
+				"service.beta.kubernetes.io/aws-load-balancer-target-group-attributes":  "stickiness.enabled=true,stickiness.type=source_ip",
+				"service.beta.kubernetes.io/aws-load-balancer-additional-resource-tags": resourceTagsFor(cp),
 			},
 		},
 		Spec: v1.ServiceSpec{
 			Type:     v1.ServiceTypeLoadBalancer,
 			Selector: labelsFor(cp.ClusterName()),
-			Ports: []v1.ServicePort{{
-				Port:       443,
-				Name:       apiserverPortName(cp.ClusterName()),
-				TargetPort: intstr.IntOrString{Type: intstr.Int, IntVal: 443},
-				Protocol:   "TCP",
-			}},
+			Ports:    ports,
 		},
 	}))
 }
 
+// Endpoint returns the URL of the apiserver load balancer of the cluster.
+func (c *Controller) Endpoint(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (string, error) {
+	endpoint, err := c.getClusterEndpoint(ctx, object.NamespacedName(controlPlane.ClusterName(), controlPlane.Namespace))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s:443", endpoint), nil
+}
+
 func (c *Controller) getClusterEndpoint(ctx context.Context, nn types.NamespacedName) (string, error) {
 	svc := &v1.Service{}
-	if err := c.kubeClient.Get(ctx, types.NamespacedName{nn.Namespace, ServiceNameFor(nn.Name)}, svc); err != nil {
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Namespace: nn.Namespace, Name: ServiceNameFor(nn.Name)}, svc); err != nil {
 		if errors.IsNotFound(err) {
 			return "", fmt.Errorf("getting control plane endpoint, %w", errors.WaitingForSubResources)
 		}
@@ -65,6 +87,17 @@ func (c *Controller) getClusterEndpoint(ctx context.Context, nn types.Namespaced
 	return "", fmt.Errorf("endpoint name, %w", errors.WaitingForSubResources)
 }
 
+// resourceTagsFor returns the tags of the load balancer and its target groups
+// in the format of the annotation, e.g. kit.aws/cluster=default/foo,kit.aws/managed=true
+func resourceTagsFor(controlPlane *v1alpha1.ControlPlane) string {
+	var tags []string
+	for key, value := range guard.TagsFor(controlPlane) {
+		tags = append(tags, key+"="+value)
+	}
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
+
 func apiserverPortName(clusterName string) string {
 	return fmt.Sprintf("%s-port", ServiceNameFor(clusterName))
 }
@@ -0,0 +1,53 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// withFeatureGates adds the feature gates for the cluster to the component
+// container, runtime config is only understood by the apiserver so it's only
+// added when withRuntimeConfig is set.
+func withFeatureGates(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec, withRuntimeConfig bool) v1.PodSpec {
+	container := &spec.Containers[0]
+	if len(controlPlane.Spec.Master.FeatureGates) > 0 {
+		gates := map[string]string{}
+		for gate, enabled := range controlPlane.Spec.Master.FeatureGates {
+			gates[gate] = fmt.Sprint(enabled)
+		}
+		container.Args = append(container.Args, "--feature-gates="+joinSorted(gates))
+	}
+	if withRuntimeConfig && len(controlPlane.Spec.Master.RuntimeConfig) > 0 {
+		container.Args = append(container.Args, "--runtime-config="+joinSorted(controlPlane.Spec.Master.RuntimeConfig))
+	}
+	return spec
+}
+
+// joinSorted returns key=value pairs separated by commas, sorted by key so the
+// args don't change between reconciles and roll the deployment.
+func joinSorted(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for key, value := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
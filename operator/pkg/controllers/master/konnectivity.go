@@ -0,0 +1,163 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// KonnectivityAgentPort is the port the agents connect to the server on,
+	// through the control plane load balancer
+	KonnectivityAgentPort = 8132
+	// KonnectivityAudience is the audience of the service account tokens the
+	// agents authenticate with
+	KonnectivityAudience = "system:konnectivity-server"
+
+	konnectivityServerImage  = "k8s.gcr.io/kas-network-proxy/proxy-server:v0.0.16"
+	konnectivityConfigKey    = "egress-selector-configuration.yaml"
+	konnectivityConfigPath   = "/etc/kubernetes/konnectivity"
+	konnectivitySocketPath   = "/etc/kubernetes/konnectivity-server"
+	konnectivityKubeConfig   = "/etc/kubernetes/config/konnectivity"
+	konnectivityEgressConfig = `apiVersion: apiserver.k8s.io/v1beta1
+kind: EgressSelectorConfiguration
+egressSelections:
+- name: cluster
+  connection:
+    proxyProtocol: GRPC
+    transport:
+      uds:
+        udsName: ` + konnectivitySocketPath + `/konnectivity-server.socket
+`
+)
+
+// KonnectivityEnabled returns true if the apiserver to node traffic of the
+// cluster goes through konnectivity
+func KonnectivityEnabled(controlPlane *v1alpha1.ControlPlane) bool {
+	return controlPlane.Spec.Master.Konnectivity != nil && controlPlane.Spec.Master.Konnectivity.Enabled
+}
+
+// reconcileKonnectivityConfig stores the egress selector config pointing the
+// apiserver at the konnectivity server socket
+func (c *Controller) reconcileKonnectivityConfig(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	if !KonnectivityEnabled(controlPlane) {
+		return nil
+	}
+	if err := c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      KonnectivityConfigNameFor(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+		},
+		Data: map[string]string{konnectivityConfigKey: konnectivityEgressConfig},
+	})); err != nil {
+		return fmt.Errorf("ensuring konnectivity config, %w", err)
+	}
+	return nil
+}
+
+// withKonnectivity runs the konnectivity server as a sidecar of the apiserver,
+// they talk over a unix socket on a volume shared by both containers. The
+// server validates the agent tokens with the admin kubeconfig. The agents keep
+// connecting through the load balancer until they're connected to as many
+// servers as the server count, each server is told apart by its pod name.
+func withKonnectivity(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec) v1.PodSpec {
+	if !KonnectivityEnabled(controlPlane) {
+		return spec
+	}
+	socket := v1.VolumeMount{Name: "konnectivity-uds", MountPath: konnectivitySocketPath}
+	apiserver := &spec.Containers[0]
+	apiserver.Args = append(apiserver.Args, fmt.Sprintf("--egress-selector-config-file=%s/%s", konnectivityConfigPath, konnectivityConfigKey))
+	apiserver.VolumeMounts = append(apiserver.VolumeMounts, socket, v1.VolumeMount{
+		Name:      "konnectivity-config",
+		MountPath: konnectivityConfigPath,
+		ReadOnly:  true,
+	})
+	spec.Containers = append(spec.Containers, v1.Container{
+		Name:    "konnectivity-server",
+		Image:   images.Mirror(controlPlane.Spec.ImageRegistry, konnectivityServerImage),
+		Command: []string{"/proxy-server"},
+		Args: []string{
+			"--logtostderr=true",
+			"--server-id=$(POD_NAME)",
+			fmt.Sprintf("--server-count=%d", *replicasFor(controlPlane)),
+			"--uds-name=" + konnectivitySocketPath + "/konnectivity-server.socket",
+			"--cluster-cert=/etc/kubernetes/pki/apiserver/apiserver.crt",
+			"--cluster-key=/etc/kubernetes/pki/apiserver/apiserver.key",
+			"--mode=grpc",
+			"--server-port=0",
+			fmt.Sprintf("--agent-port=%d", KonnectivityAgentPort),
+			"--admin-port=8133",
+			"--health-port=8134",
+			"--agent-namespace=kube-system",
+			"--agent-service-account=konnectivity-agent",
+			"--authentication-audience=" + KonnectivityAudience,
+			fmt.Sprintf("--kubeconfig=%s/%s", konnectivityKubeConfig, secrets.SecretConfigKey),
+		},
+		LivenessProbe: &v1.Probe{
+			Handler: v1.Handler{HTTPGet: &v1.HTTPGetAction{
+				Path: "/healthz",
+				Port: intstr.FromInt(8134),
+			}},
+			InitialDelaySeconds: 30,
+			TimeoutSeconds:      60,
+		},
+		Env: []v1.EnvVar{{
+			Name: "POD_NAME",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		}},
+		Ports: []v1.ContainerPort{{Name: "agent", ContainerPort: KonnectivityAgentPort, Protocol: v1.ProtocolTCP}},
+		VolumeMounts: []v1.VolumeMount{socket, {
+			Name:      "apiserver",
+			MountPath: "/etc/kubernetes/pki/apiserver",
+			ReadOnly:  true,
+		}, {
+			Name:      "konnectivity-kubeconfig",
+			MountPath: konnectivityKubeConfig,
+			ReadOnly:  true,
+		}},
+	})
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name:         "konnectivity-uds",
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	}, v1.Volume{
+		Name: "konnectivity-config",
+		VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: v1.LocalObjectReference{Name: KonnectivityConfigNameFor(controlPlane.ClusterName())},
+		}},
+	}, v1.Volume{
+		Name: "konnectivity-kubeconfig",
+		VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{
+			SecretName: KubeAdminSecretNameFor(controlPlane.ClusterName()),
+		}},
+	})
+	return spec
+}
+
+func KonnectivityConfigNameFor(clusterName string) string {
+	return fmt.Sprintf("%s-konnectivity", clusterName)
+}
@@ -19,8 +19,10 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/config"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/patch"
 	appsv1 "k8s.io/api/apps/v1"
@@ -29,38 +31,37 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	apiserverImage        = "public.ecr.aws/eks-distro/kubernetes/kube-apiserver:v1.20.7-eks-1-20-4"
-	serviceClusterIPRange = "10.96.0.0/12"
-)
-
 func (c *Controller) reconcileApiServer(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (err error) {
-	apiServerPodSpec := apiServerPodSpecFor(controlPlane)
+	if err := c.reconcileKonnectivityConfig(ctx, controlPlane); err != nil {
+		return err
+	}
+	apiServerPodSpec := WithProxy(controlPlane, withBinaryFrom(controlPlane, controlPlane.Spec.Master.APIServer,
+		withCloudProvider(controlPlane, withFeatureGates(controlPlane, withKonnectivity(controlPlane, apiServerPodSpecFor(controlPlane)), true),
+			v1alpha1.CloudProviderAWS)))
 	if controlPlane.Spec.Master.APIServer != nil {
 		apiServerPodSpec, err = patch.PodSpec(&apiServerPodSpec, controlPlane.Spec.Master.APIServer.Spec)
 		if err != nil {
 			return fmt.Errorf("patch api server pod spec, %w", err)
 		}
 	}
-	return c.kubeClient.EnsurePatch(ctx, &appsv1.Deployment{},
-		object.WithOwner(controlPlane, &appsv1.Deployment{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      APIServerDeploymentName(controlPlane.ClusterName()),
-				Namespace: controlPlane.Namespace,
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      APIServerDeploymentName(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: apiServerLabels(controlPlane.ClusterName()),
 			},
-			Spec: appsv1.DeploymentSpec{
-				Selector: &metav1.LabelSelector{
-					MatchLabels: apiServerLabels(controlPlane.ClusterName()),
-				},
-				Replicas: aws.Int32(3),
-				Template: v1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: apiServerLabels(controlPlane.ClusterName()),
-					},
-					Spec: apiServerPodSpec,
+			Replicas: replicasFor(controlPlane),
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: apiServerLabels(controlPlane.ClusterName()),
 				},
+				Spec: apiServerPodSpec,
 			},
-		}))
+		},
+	}))
 }
 
 func APIServerDeploymentName(clusterName string) string {
@@ -79,8 +80,10 @@ func apiServerPodSpecFor(controlPlane *v1alpha1.ControlPlane) v1.PodSpec {
 		TerminationGracePeriodSeconds: aws.Int64(1),
 		HostNetwork:                   true,
 		DNSPolicy:                     v1.DNSClusterFirstWithHostNet,
-		PriorityClassName:             "system-cluster-critical",
-		NodeSelector:                  nodeSelector(controlPlane.ClusterName()),
+		PriorityClassName:             isolation.PriorityClassNameFor(controlPlane, "system-cluster-critical"),
+		NodeSelector:                  nodeSelector(controlPlane),
+		Tolerations:                   isolation.TolerationsFor(controlPlane),
+		ImagePullSecrets:              controlPlane.Spec.ImagePullSecrets,
 		TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
 			MaxSkew:           int32(1),
 			TopologyKey:       "topology.kubernetes.io/zone",
@@ -99,7 +102,7 @@ func apiServerPodSpecFor(controlPlane *v1alpha1.ControlPlane) v1.PodSpec {
 		Containers: []v1.Container{
 			{
 				Name:    "apiserver",
-				Image:   apiserverImage,
+				Image:   imageFor(controlPlane, controlPlane.Spec.Master.APIServer, config.DefaultAPIServerImage),
 				Command: []string{"kube-apiserver"},
 				Resources: v1.ResourceRequirements{
 					Requests: map[v1.ResourceName]resource.Quantity{
@@ -132,7 +135,7 @@ func apiServerPodSpecFor(controlPlane *v1alpha1.ControlPlane) v1.PodSpec {
 					"--service-account-issuer=https://kubernetes.default.svc.cluster.local",
 					"--service-account-key-file=/etc/kubernetes/pki/sa/sa.pub",
 					"--service-account-signing-key-file=/etc/kubernetes/pki/sa/sa.key",
-					"--service-cluster-ip-range=" + serviceClusterIPRange,
+					"--service-cluster-ip-range=" + controlPlane.ServiceCIDR(),
 					"--tls-cert-file=/etc/kubernetes/pki/apiserver/apiserver.crt",
 					"--tls-private-key-file=/etc/kubernetes/pki/apiserver/apiserver.key",
 				},
@@ -23,10 +23,12 @@ import (
 	"github.com/awslabs/kit/operator/pkg/errors"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/secrets"
-	"go.uber.org/zap"
+	"knative.dev/pkg/logging"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
 	certutil "k8s.io/client-go/util/cert"
@@ -49,46 +51,90 @@ func (c *Controller) reconcileKubeConfigs(ctx context.Context, controlPlane *v1a
 	if err != nil {
 		return err
 	}
-	for _, request := range []*configRequest{
+	requests := []*configRequest{
 		kubeAdminCertConfig(controlPlane.ClusterName(), endpoint, caSecret),
 		kubeSchedulerCertConfig(controlPlane.ClusterName(), localhostEndpoint, caSecret),
 		kubeControllerManagerCertConfig(controlPlane.ClusterName(), localhostEndpoint, caSecret),
-	} {
+	}
+	if controlPlane.Spec.Master.CloudProvider == v1alpha1.CloudProviderExternal {
+		requests = append(requests, kubeCloudControllerManagerCertConfig(controlPlane.ClusterName(), localhostEndpoint, caSecret))
+	}
+	for _, request := range requests {
 		if err := c.reconcileConfigFor(ctx, controlPlane, request); err != nil {
 			return err
 		}
 	}
-	zap.S().Debugf("[%v] Kube configs reconciled", controlPlane.ClusterName())
+	logging.FromContext(ctx).Debug("Kube configs reconciled")
 	return nil
 }
 
 func (c *Controller) reconcileConfigFor(ctx context.Context, controlPlane *v1alpha1.ControlPlane, request *configRequest) error {
-	clusterName := controlPlane.ClusterName()
 	namespace := controlPlane.Namespace
 	// Check if this secret for kubeconfig exists in the api server
-	_, err := c.keypairs.GetSecretFromServer(ctx, object.NamespacedName(request.auth.Name, namespace))
+	existing, err := c.keypairs.GetSecretFromServer(ctx, object.NamespacedName(request.auth.Name, namespace))
 	if err != nil && errors.IsNotFound(err) {
-		// Generate the cert and key for the user
-		secret, err := request.auth.Create()
-		if err != nil {
-			return fmt.Errorf("creating cert and key for %v, %w", request.auth.CommonName, err)
-		}
-		// certs generated for clients (admin, KCM, scheduler) are stored in the kubeconfig format.
-		// generate kubeconfig for this is client and convert to YAML
-		configBytes, err := runtime.Encode(clientcmdlatest.Codec, kubeConfigFor(request, clusterName, secret))
+		secret, err := configSecretFor(request, controlPlane)
 		if err != nil {
-			return fmt.Errorf("encoding kube config object %v, %w", request.auth.CommonName, err)
+			return err
 		}
 		// Create a secret object with config and ensure the secret object is in the api server
-		if err := c.kubeClient.EnsureCreate(ctx, object.WithOwner(controlPlane,
-			secrets.CreateWithConfig(object.NamespacedName(request.auth.Name, namespace), configBytes)),
-		); err != nil {
+		if err := c.kubeClient.EnsureCreate(ctx, object.WithOwner(controlPlane, secret)); err != nil {
 			return fmt.Errorf("ensuring kube config for %v, %w", request.auth.CommonName, err)
 		}
 		return nil
 	}
-	// TODO validate the existing config in the secret
-	return err
+	if err != nil {
+		return err
+	}
+	// Configs issued with other groups are reissued, e.g. the cloud controller
+	// manager config of older clusters in system:masters. The component reads
+	// the new config when it restarts.
+	if sameGroups(request, existing) {
+		return nil
+	}
+	secret, err := configSecretFor(request, controlPlane)
+	if err != nil {
+		return err
+	}
+	existing.Data = secret.Data
+	if err := c.kubeClient.Update(ctx, existing); err != nil {
+		return fmt.Errorf("reissuing kube config for %v, %w", request.auth.CommonName, err)
+	}
+	logging.FromContext(ctx).Infof("Reissued kube config %s with groups %v", existing.Name, request.auth.Organization)
+	return nil
+}
+
+func configSecretFor(request *configRequest, controlPlane *v1alpha1.ControlPlane) (*v1.Secret, error) {
+	// Generate the cert and key for the user
+	secret, err := request.auth.Create()
+	if err != nil {
+		return nil, fmt.Errorf("creating cert and key for %v, %w", request.auth.CommonName, err)
+	}
+	// certs generated for clients (admin, KCM, scheduler) are stored in the kubeconfig format.
+	// generate kubeconfig for this is client and convert to YAML
+	configBytes, err := runtime.Encode(clientcmdlatest.Codec, kubeConfigFor(request, controlPlane.ClusterName(), secret))
+	if err != nil {
+		return nil, fmt.Errorf("encoding kube config object %v, %w", request.auth.CommonName, err)
+	}
+	return secrets.CreateWithConfig(object.NamespacedName(request.auth.Name, controlPlane.Namespace), configBytes), nil
+}
+
+// sameGroups is true when the client cert of the kubeconfig in secret has the
+// groups of the request, or can't be parsed
+func sameGroups(request *configRequest, secret *v1.Secret) bool {
+	config, err := clientcmd.Load(secret.Data[secrets.SecretConfigKey])
+	if err != nil {
+		return true
+	}
+	authInfo, ok := config.AuthInfos[request.auth.Name]
+	if !ok {
+		return true
+	}
+	certs, err := certutil.ParseCertsPEM(authInfo.ClientCertificateData)
+	if err != nil || len(certs) == 0 {
+		return true
+	}
+	return sets.NewString(certs[0].Subject.Organization...).Equal(sets.NewString(request.auth.Organization...))
 }
 
 func kubeConfigFor(request *configRequest, clusterName string, userSecret *v1.Secret) *clientcmdapi.Config {
@@ -19,7 +19,9 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/config"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/patch"
 	appsv1 "k8s.io/api/apps/v1"
@@ -28,12 +30,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	controllerManagerImage = "public.ecr.aws/eks-distro/kubernetes/kube-controller-manager:v1.20.7-eks-1-20-4"
-)
-
 func (c *Controller) reconcileKCM(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
-	return c.kubeClient.EnsurePatch(ctx, &appsv1.Deployment{}, object.WithOwner(controlPlane, kcmDeploymentSpec(controlPlane)))
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, kcmDeploymentSpec(controlPlane)))
 }
 
 func kcmDeploymentSpec(controlPlane *v1alpha1.ControlPlane) *appsv1.Deployment {
@@ -46,17 +44,33 @@ func kcmDeploymentSpec(controlPlane *v1alpha1.ControlPlane) *appsv1.Deployment {
 			Selector: &metav1.LabelSelector{
 				MatchLabels: kcmLabels(controlPlane.ClusterName()),
 			},
-			Replicas: aws.Int32(3),
+			Replicas: replicasFor(controlPlane),
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: kcmLabels(controlPlane.ClusterName()),
 				},
-				Spec: *kcmPodSpecFor(controlPlane),
+				Spec: WithProxy(controlPlane, withBinaryFrom(controlPlane, controlPlane.Spec.Master.ControllerManager,
+					withCloudProvider(controlPlane, withFeatureGates(controlPlane, withNodeCIDRs(controlPlane, *kcmPodSpecFor(controlPlane)), false),
+						v1alpha1.CloudProviderAWS, v1alpha1.CloudProviderExternal))),
 			},
 		},
 	}
 }
 
+// withNodeCIDRs has the controller manager allocate a podCIDR to each node
+// from the pod CIDR of the cluster, when it has one
+func withNodeCIDRs(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec) v1.PodSpec {
+	if controlPlane.PodCIDR() == "" {
+		return spec
+	}
+	spec.Containers[0].Args = append(spec.Containers[0].Args,
+		"--allocate-node-cidrs=true",
+		"--cluster-cidr="+controlPlane.PodCIDR(),
+		"--service-cluster-ip-range="+controlPlane.ServiceCIDR(),
+	)
+	return spec
+}
+
 func KCMDeploymentName(clusterName string) string {
 	return fmt.Sprintf("%s-controller-manager", clusterName)
 }
@@ -71,8 +85,10 @@ func kcmPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		TerminationGracePeriodSeconds: aws.Int64(1),
 		HostNetwork:                   true,
 		DNSPolicy:                     v1.DNSClusterFirstWithHostNet,
-		PriorityClassName:             "system-node-critical",
-		NodeSelector:                  nodeSelector(controlPlane.ClusterName()),
+		PriorityClassName:             isolation.PriorityClassNameFor(controlPlane, "system-node-critical"),
+		NodeSelector:                  nodeSelector(controlPlane),
+		Tolerations:                   isolation.TolerationsFor(controlPlane),
+		ImagePullSecrets:              controlPlane.Spec.ImagePullSecrets,
 		TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
 			MaxSkew:           int32(1),
 			TopologyKey:       "topology.kubernetes.io/zone",
@@ -96,7 +112,7 @@ func kcmPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		}},
 		Containers: []v1.Container{{
 			Name:    "controller-manager",
-			Image:   controllerManagerImage,
+			Image:   imageFor(controlPlane, controlPlane.Spec.Master.ControllerManager, config.DefaultControllerManagerImage),
 			Command: []string{"kube-controller-manager"},
 			Resources: v1.ResourceRequirements{
 				Requests: map[v1.ResourceName]resource.Quantity{
@@ -108,7 +124,7 @@ func kcmPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 				"--authorization-kubeconfig=/etc/kubernetes/config/kcm/controller-manager.conf",
 				"--bind-address=127.0.0.1",
 				"--client-ca-file=/etc/kubernetes/pki/ca/ca.crt",
-				"--cluster-name=kubernetes",
+				"--cluster-name=" + kcmClusterName(controlPlane),
 				"--cluster-signing-cert-file=/etc/kubernetes/pki/ca/ca.crt",
 				"--cluster-signing-key-file=/etc/kubernetes/pki/ca/ca.key",
 				"--controllers=*,bootstrapsigner,tokencleaner",
@@ -207,3 +223,12 @@ func kcmPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		}},
 	}
 }
+
+// kcmClusterName is the name KCM tags AWS resources with, KCM of clusters with
+// a legacy name was started with the default name and keeps it
+func kcmClusterName(controlPlane *v1alpha1.ControlPlane) string {
+	if controlPlane.HasLegacyClusterName() {
+		return "kubernetes"
+	}
+	return controlPlane.AWSClusterName()
+}
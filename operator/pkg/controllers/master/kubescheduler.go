@@ -19,7 +19,9 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/config"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -27,15 +29,22 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-const (
-	schedulerImage = "public.ecr.aws/eks-distro/kubernetes/kube-scheduler:v1.20.7-eks-1-20-4"
-)
-
 func (c *Controller) reconcileScheduler(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
-	return c.kubeClient.EnsurePatch(ctx, &appsv1.Deployment{}, object.WithOwner(controlPlane, schedulerDeploymentSpec(controlPlane)))
+	if err := c.reconcileSchedulerConfig(ctx, controlPlane); err != nil {
+		return err
+	}
+	checksum, err := c.schedulerConfigChecksum(ctx, controlPlane)
+	if err != nil {
+		return err
+	}
+	return c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, schedulerDeploymentSpec(controlPlane, checksum)))
 }
 
-func schedulerDeploymentSpec(controlPlane *v1alpha1.ControlPlane) *appsv1.Deployment {
+func schedulerDeploymentSpec(controlPlane *v1alpha1.ControlPlane, configChecksum string) *appsv1.Deployment {
+	var annotations map[string]string
+	if configChecksum != "" {
+		annotations = map[string]string{object.ChecksumAnnotationKey: configChecksum}
+	}
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      SchedulerDeploymentName(controlPlane.ClusterName()),
@@ -45,12 +54,14 @@ func schedulerDeploymentSpec(controlPlane *v1alpha1.ControlPlane) *appsv1.Deploy
 			Selector: &metav1.LabelSelector{
 				MatchLabels: schedulerLabels(controlPlane.ClusterName()),
 			},
-			Replicas: aws.Int32(3),
+			Replicas: replicasFor(controlPlane),
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: schedulerLabels(controlPlane.ClusterName()),
+					Labels:      schedulerLabels(controlPlane.ClusterName()),
+					Annotations: annotations,
 				},
-				Spec: *schedulerPodSpecFor(controlPlane),
+				Spec: withBinaryFrom(controlPlane, controlPlane.Spec.Master.Scheduler,
+					withSchedulerConfig(controlPlane, withFeatureGates(controlPlane, *schedulerPodSpecFor(controlPlane), false))),
 			},
 		},
 	}
@@ -72,8 +83,10 @@ func schedulerPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		TerminationGracePeriodSeconds: aws.Int64(1),
 		HostNetwork:                   true,
 		DNSPolicy:                     v1.DNSClusterFirstWithHostNet,
-		PriorityClassName:             "system-node-critical",
-		NodeSelector:                  nodeSelector(controlPlane.ClusterName()),
+		PriorityClassName:             isolation.PriorityClassNameFor(controlPlane, "system-node-critical"),
+		NodeSelector:                  nodeSelector(controlPlane),
+		Tolerations:                   isolation.TolerationsFor(controlPlane),
+		ImagePullSecrets:              controlPlane.Spec.ImagePullSecrets,
 		TopologySpreadConstraints: []v1.TopologySpreadConstraint{{
 			MaxSkew:           int32(1),
 			TopologyKey:       "topology.kubernetes.io/zone",
@@ -97,7 +110,7 @@ func schedulerPodSpecFor(controlPlane *v1alpha1.ControlPlane) *v1.PodSpec {
 		}},
 		Containers: []v1.Container{{
 			Name:    "scheduler",
-			Image:   schedulerImage,
+			Image:   imageFor(controlPlane, controlPlane.Spec.Master.Scheduler, config.DefaultSchedulerImage),
 			Command: []string{"kube-scheduler"},
 			Resources: v1.ResourceRequirements{
 				Requests: map[v1.ResourceName]resource.Quantity{
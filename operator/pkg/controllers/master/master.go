@@ -17,12 +17,21 @@ package master
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	"github.com/awslabs/kit/operator/pkg/kubeprovider"
+	"github.com/awslabs/kit/operator/pkg/utils/images"
 	"github.com/awslabs/kit/operator/pkg/utils/keypairs"
+	"github.com/awslabs/kit/operator/pkg/utils/isolation"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/patch"
-	"go.uber.org/zap"
+	"github.com/awslabs/kit/operator/pkg/utils/reconciler"
+	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	defaultReplicas = 3
 )
 
 type Controller struct {
@@ -34,30 +43,55 @@ func New(kubeclient *kubeprovider.Client) *Controller {
 	return &Controller{kubeClient: kubeclient, keypairs: keypairs.Reconciler(kubeclient)}
 }
 
-type reconciler func(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (err error)
-
+// Reconcile runs in stages, the reconcilers within a stage don't depend on
+// each other and run in parallel.
 func (c *Controller) Reconcile(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
-	for _, reconcile := range []reconciler{
-		c.reconcileEndpoint,
-		c.reconcileCertificates,
-		c.reconcileKubeConfigs,
-		c.reconcileSAKeyPair,
-		c.reconcileApiServer,
-		c.reconcileKCM,
-		c.reconcileScheduler,
+	for _, stage := range [][]reconciler.Func{
+		{c.reconcileEndpoint},
+		{c.reconcileCertificates, c.reconcileSAKeyPair},
+		{c.reconcileKubeConfigs},
+		{c.reconcileClusterAPIKubeConfig, c.reconcileApiServer, c.reconcileKCM, c.reconcileCCM, c.reconcileScheduler, c.reconcileDisruptionBudgets},
 	} {
-		if err := reconcile(ctx, controlPlane); err != nil {
+		if err := reconciler.Parallel(ctx, controlPlane, stage...); err != nil {
 			return err
 		}
 	}
-	zap.S().Infof("[%v] control plane reconciled", controlPlane.ClusterName())
+	logging.FromContext(ctx).Info("control plane reconciled")
 	return nil
 }
 
+// replicasFor returns the desired number of replicas for the master
+// components, a paused control plane runs no master pods.
+func replicasFor(controlPlane *v1alpha1.ControlPlane) *int32 {
+	if controlPlane.Spec.Paused {
+		return aws.Int32(0)
+	}
+	return aws.Int32(defaultReplicas)
+}
+
+// InstancesFor returns the number of master instances of the control plane,
+// the replicas of each master component are spread one per node.
+func InstancesFor(controlPlane *v1alpha1.ControlPlane) int32 {
+	return *replicasFor(controlPlane)
+}
+
+// imageFor returns the image the user provided for the component if any, else
+// the default image
+func imageFor(controlPlane *v1alpha1.ControlPlane, component *v1alpha1.Component, defaultImage string) string {
+	if component != nil && component.Image != "" {
+		return component.Image
+	}
+	return images.Mirror(controlPlane.Spec.ImageRegistry, defaultImage)
+}
+
 // Karpenter only created nodes for API server pods, as KCM and scheduler pods
 // are configured with pod afinity. So the control plane nodes for a cluster
 // will have 2 labels cluster name and clustername-apiserver
-func nodeSelector(clusterName string) map[string]string {
-	return patch.UnionStringMaps(apiServerLabels(clusterName),
-		map[string]string{object.ControlPlaneLabelKey: clusterName})
+func nodeSelector(controlPlane *v1alpha1.ControlPlane) map[string]string {
+	selector := patch.UnionStringMaps(apiServerLabels(controlPlane.ClusterName()),
+		map[string]string{object.ControlPlaneLabelKey: controlPlane.AWSClusterName()})
+	if architecture := controlPlane.Spec.Master.Architecture; architecture != "" {
+		selector[v1.LabelArchStable] = architecture
+	}
+	return isolation.NodeSelectorFor(controlPlane, selector)
 }
@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// VPCCIDRs returns the CIDRs of the VPC of the operator, the nodes of the
+// clusters are launched in it. It's only called for clusters with a proxy and
+// returns nothing when the operator doesn't run on EC2.
+var VPCCIDRs = func() []string { return nil }
+
+// WithProxy sets the proxy environment variables on the containers of
+// components reaching outside the management cluster, like AWS APIs or
+// webhooks. Both cases are set as curl only reads the lowercase http_proxy.
+// The cluster's own services and pods, the nodes in the VPC and the cluster
+// endpoint are always reached directly.
+func WithProxy(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec) v1.PodSpec {
+	proxy := controlPlane.Spec.Proxy
+	if proxy == nil {
+		return spec
+	}
+	var env []v1.EnvVar
+	for _, variable := range [][2]string{
+		{"HTTP_PROXY", proxy.HTTPProxy},
+		{"HTTPS_PROXY", proxy.HTTPSProxy},
+		{"NO_PROXY", strings.Join(append(noProxyFor(controlPlane), proxy.NoProxy...), ",")},
+	} {
+		if variable[1] == "" {
+			continue
+		}
+		env = append(env,
+			v1.EnvVar{Name: variable[0], Value: variable[1]},
+			v1.EnvVar{Name: strings.ToLower(variable[0]), Value: variable[1]})
+	}
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Env = append(spec.InitContainers[i].Env, env...)
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].Env = append(spec.Containers[i].Env, env...)
+	}
+	return spec
+}
+
+func noProxyFor(controlPlane *v1alpha1.ControlPlane) []string {
+	noProxy := []string{"localhost", "127.0.0.1", ".svc", ".cluster.local", controlPlane.ServiceCIDR()}
+	if podCIDR := controlPlane.PodCIDR(); podCIDR != "" {
+		noProxy = append(noProxy, podCIDR)
+	}
+	noProxy = append(noProxy, VPCCIDRs()...)
+	if endpoint, err := url.Parse(controlPlane.Status.Endpoint); err == nil && endpoint.Hostname() != "" {
+		noProxy = append(noProxy, endpoint.Hostname())
+	}
+	return noProxy
+}
@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	schedulerConfigKey    = "config.yaml"
+	schedulerConfigPath   = "/etc/kubernetes/config/scheduler-profile"
+	schedulerConfigVolume = "scheduler-profile"
+)
+
+// reconcileSchedulerConfig stores an inline scheduler config in a config map
+// so that it can be mounted in the scheduler pod like a referenced one.
+func (c *Controller) reconcileSchedulerConfig(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	config := controlPlane.Spec.Master.SchedulerConfig
+	if config == nil || config.Inline == "" {
+		return nil
+	}
+	if err := c.kubeClient.EnsureApply(ctx, object.WithOwner(controlPlane, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SchedulerConfigNameFor(controlPlane.ClusterName()),
+			Namespace: controlPlane.Namespace,
+		},
+		Data: map[string]string{schedulerConfigKey: config.Inline},
+	})); err != nil {
+		return fmt.Errorf("ensuring scheduler config, %w", err)
+	}
+	return nil
+}
+
+// schedulerConfigChecksum hashes the scheduler config, inline or referenced,
+// so the scheduler pods restart when it changes. It's empty without a config
+// or when the referenced config map doesn't exist yet.
+func (c *Controller) schedulerConfigChecksum(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (string, error) {
+	config := controlPlane.Spec.Master.SchedulerConfig
+	if config == nil {
+		return "", nil
+	}
+	data := config.Inline
+	if config.ConfigMapRef != nil {
+		configMap := &v1.ConfigMap{}
+		if err := c.kubeClient.Get(ctx, object.NamespacedName(config.ConfigMapRef.Name, controlPlane.Namespace), configMap); err != nil {
+			if errors.IsNotFound(err) {
+				return "", nil
+			}
+			return "", fmt.Errorf("getting scheduler config %s, %w", config.ConfigMapRef.Name, err)
+		}
+		data = configMap.Data[config.ConfigMapRef.Key]
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(data))
+	return fmt.Sprintf("%016x", hash.Sum64()), nil
+}
+
+// withSchedulerConfig mounts the scheduler config in the scheduler container
+// and points --config at it
+func withSchedulerConfig(controlPlane *v1alpha1.ControlPlane, spec v1.PodSpec) v1.PodSpec {
+	config := controlPlane.Spec.Master.SchedulerConfig
+	if config == nil {
+		return spec
+	}
+	selector := v1.ConfigMapKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: SchedulerConfigNameFor(controlPlane.ClusterName())},
+		Key:                  schedulerConfigKey,
+	}
+	if config.ConfigMapRef != nil {
+		selector = *config.ConfigMapRef
+	}
+	container := &spec.Containers[0]
+	container.Args = append(container.Args, fmt.Sprintf("--config=%s/%s", schedulerConfigPath, schedulerConfigKey))
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      schedulerConfigVolume,
+		MountPath: schedulerConfigPath,
+		ReadOnly:  true,
+	})
+	spec.Volumes = append(spec.Volumes, v1.Volume{
+		Name: schedulerConfigVolume,
+		VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{
+			LocalObjectReference: selector.LocalObjectReference,
+			Items:                []v1.KeyToPath{{Key: selector.Key, Path: schedulerConfigKey}},
+		}},
+	})
+	return spec
+}
+
+func SchedulerConfigNameFor(clusterName string) string {
+	return fmt.Sprintf("%s-scheduler-profile", clusterName)
+}
@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// StallTimeout is how long a resource can wait on a dependency before it's
+// marked as Stalled
+var StallTimeout = 30 * time.Minute
+
+var stalledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "kit",
+	Subsystem: "controller",
+	Name:      "stalled_total",
+	Help:      "Number of times a resource was marked as stalled waiting on a dependency",
+}, []string{"controller"})
+
+func init() {
+	metrics.Registry.MustRegister(stalledTotal)
+}
+
+// detectStall marks the resource as Stalled once it has been waiting on a
+// dependency for longer than StallTimeout, and clears the condition as soon as
+// the reconcile gets past it.
+func (c *GenericController) detectStall(ctx context.Context, resource Object, reconcileErr error) {
+	if reconcileErr == nil || !errors.IsWaitingForSubResource(reconcileErr) {
+		_ = resource.StatusConditions().ClearCondition(v1alpha1.Stalled)
+		return
+	}
+	active := resource.StatusConditions().GetCondition(v1alpha1.Active)
+	if active == nil || !active.IsFalse() || time.Since(active.LastTransitionTime.Inner.Time) < StallTimeout {
+		return
+	}
+	if stalled := resource.StatusConditions().GetCondition(v1alpha1.Stalled); stalled != nil && stalled.IsTrue() {
+		return
+	}
+	resource.StatusConditions().MarkTrueWithReason(v1alpha1.Stalled, "WaitingForSubResources", reconcileErr.Error())
+	stalledTotal.WithLabelValues(c.Name()).Inc()
+	logging.FromContext(ctx).Errorf("Stalled for over %s, %s", StallTimeout, reconcileErr.Error())
+	if c.Recorder != nil {
+		c.Recorder.Eventf(resource, v1.EventTypeWarning, "Stalled", "Waiting for over %s, %s", StallTimeout, reconcileErr.Error())
+	}
+}
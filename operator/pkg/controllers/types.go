@@ -43,6 +43,14 @@ type Controller interface {
 	For() Object
 }
 
+// Owner is implemented by controllers which create objects owned by their
+// resource with object.WithOwner, a change to an owned object triggers a
+// reconcile of its owner.
+type Owner interface {
+	// Owns returns a default instantiation of each type of the owned objects.
+	Owns() []client.Object
+}
+
 // Webhook implements both a handler and path and can be attached to a webhook server.
 type Webhook interface {
 	webhook.AdmissionHandler
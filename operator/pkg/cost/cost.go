@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cost
+
+import (
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+)
+
+const (
+	// networkLoadBalancerHourly is the fixed hourly charge for the NLB in front
+	// of the apiserver, LCU charges are usage based and not included.
+	networkLoadBalancerHourly = 0.0225
+)
+
+// instanceHourly contains approximate on-demand prices in USD (us-west-2) for
+// the instance types commonly used for control plane nodes. Control planes
+// with instance types not in this list aren't estimated.
+var instanceHourly = map[string]float64{
+	"t3.medium":   0.0416,
+	"t3.large":    0.0832,
+	"t3.xlarge":   0.1664,
+	"m5.large":    0.096,
+	"m5.xlarge":   0.192,
+	"m5.2xlarge":  0.384,
+	"m5.4xlarge":  0.768,
+	"m5.8xlarge":  1.536,
+	"m6g.large":   0.077,
+	"m6g.xlarge":  0.154,
+	"m6g.2xlarge": 0.308,
+	"c5.large":    0.085,
+	"c5.xlarge":   0.17,
+	"c5.2xlarge":  0.34,
+	"c5.4xlarge":  0.68,
+	"r5.large":    0.126,
+	"r5.xlarge":   0.252,
+	"r5.2xlarge":  0.504,
+	"r5.4xlarge":  1.008,
+	"i3.large":    0.156,
+	"i3.xlarge":   0.312,
+	"i3.2xlarge":  0.624,
+}
+
+// EstimateHourly returns the approximate hourly cost in USD of running the
+// given control plane, formatted for the ControlPlane status. It returns false
+// when an instance type isn't known, e.g. it isn't set and Karpenter picks it.
+func EstimateHourly(controlPlane *v1alpha1.ControlPlane) (string, bool) {
+	total := networkLoadBalancerHourly
+	for _, instances := range []struct {
+		instanceType string
+		count        int32
+	}{
+		{controlPlane.Spec.Master.Type, master.InstancesFor(controlPlane)},
+		{controlPlane.Spec.Etcd.Type, etcd.InstancesFor(controlPlane)},
+	} {
+		if instances.count == 0 {
+			continue
+		}
+		price, ok := instanceHourly[instances.instanceType]
+		if !ok {
+			return "", false
+		}
+		total += float64(instances.count) * price
+	}
+	return fmt.Sprintf("%.4f", total), true
+}
@@ -0,0 +1,235 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// State of a node in the graph
+type State string
+
+const (
+	Missing State = "Missing"
+	Pending State = "Pending"
+	Ready   State = "Ready"
+)
+
+// Node is an object KIT creates for a cluster, it can only become ready once
+// the objects it depends on are ready. Dependencies are referred to by ID.
+type Node struct {
+	Kind      string   `json:"kind"`
+	Name      string   `json:"name"`
+	State     State    `json:"state"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// WaitingOn are the dependencies which aren't ready yet
+	WaitingOn []string `json:"waitingOn,omitempty"`
+	// Created is when the object was created
+	Created *metav1.Time `json:"created,omitempty"`
+	// ReadyAfter is how long the object took to become ready once created,
+	// it is only known for objects with a condition telling when they
+	// became ready
+	ReadyAfter *metav1.Duration `json:"readyAfter,omitempty"`
+}
+
+// ID is unique within a graph, it is of the form kind/name
+func (n Node) ID() string {
+	return n.Kind + "/" + n.Name
+}
+
+// Graph is the dependency graph of the objects of a cluster
+type Graph struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+	// Conditions of the ControlPlane
+	Conditions apis.Conditions `json:"conditions,omitempty"`
+	Nodes      []Node          `json:"nodes"`
+}
+
+// For builds the dependency graph of the control plane from the live objects
+// in its namespace
+func For(ctx context.Context, kubeClient client.Client, controlPlane *v1alpha1.ControlPlane) (*Graph, error) {
+	name := controlPlane.ClusterName()
+	etcdService := Node{Kind: "Service", Name: etcd.ServiceNameFor(name)}
+	masterService := Node{Kind: "Service", Name: master.ServiceNameFor(name)}
+	etcdCerts := secretsFor([]string{etcd.CASecretNameFor(name), etcd.ServerSecretNameFor(name),
+		etcd.PeerSecretNameFor(name), etcd.EtcdAPIClientSecretNameFor(name)}, etcdService)
+	masterCerts := secretsFor([]string{master.RootCASecretNameFor(name), master.KubeAPIServerSecretNameFor(name),
+		master.KubeletClientSecretNameFor(name), master.FrontProxyCASecretNameFor(name), master.KubeFrontProxyClientSecretNameFor(name)}, masterService)
+	adminConfig := secretsFor([]string{master.KubeAdminSecretNameFor(name)}, masterCerts[0])
+	kcmConfig := secretsFor([]string{master.KubeControllerManagerSecretNameFor(name)}, masterCerts[0])
+	schedulerConfig := secretsFor([]string{master.KubeSchedulerSecretNameFor(name)}, masterCerts[0])
+	saKeyPair := Node{Kind: "Secret", Name: master.SAKeyPairSecretNameFor(name)}
+	etcdStatefulSet := Node{Kind: "StatefulSet", Name: etcd.ServiceNameFor(name), DependsOn: idsOf(etcdCerts...)}
+	apiServer := Node{Kind: "Deployment", Name: master.APIServerDeploymentName(name),
+		DependsOn: idsOf(append([]Node{etcdStatefulSet, saKeyPair, etcdCerts[3]}, masterCerts...)...)}
+	kcm := Node{Kind: "Deployment", Name: master.KCMDeploymentName(name), DependsOn: idsOf(apiServer, kcmConfig[0], saKeyPair)}
+	scheduler := Node{Kind: "Deployment", Name: master.SchedulerDeploymentName(name), DependsOn: idsOf(apiServer, schedulerConfig[0])}
+
+	nodes := []Node{etcdService}
+	nodes = append(nodes, etcdCerts...)
+	nodes = append(nodes, etcdStatefulSet, masterService)
+	nodes = append(nodes, masterCerts...)
+	nodes = append(nodes, adminConfig[0], kcmConfig[0], schedulerConfig[0], saKeyPair, apiServer, kcm, scheduler)
+	states := map[string]State{}
+	for i := range nodes {
+		if err := observe(ctx, kubeClient, controlPlane.Namespace, &nodes[i]); err != nil {
+			return nil, fmt.Errorf("getting state of %s, %w", nodes[i].ID(), err)
+		}
+		states[nodes[i].ID()] = nodes[i].State
+	}
+	for i := range nodes {
+		for _, dependency := range nodes[i].DependsOn {
+			if states[dependency] != Ready {
+				nodes[i].WaitingOn = append(nodes[i].WaitingOn, dependency)
+			}
+		}
+	}
+	return &Graph{Cluster: name, Namespace: controlPlane.Namespace, Conditions: controlPlane.Status.Conditions, Nodes: nodes}, nil
+}
+
+func secretsFor(names []string, dependsOn Node) (secrets []Node) {
+	for _, name := range names {
+		secrets = append(secrets, Node{Kind: "Secret", Name: name, DependsOn: idsOf(dependsOn)})
+	}
+	return secrets
+}
+
+func idsOf(nodes ...Node) (ids []string) {
+	for _, node := range nodes {
+		ids = append(ids, node.ID())
+	}
+	return ids
+}
+
+// observe sets the state of the node from its object, and when the object
+// tells when it became ready, how long that took
+func observe(ctx context.Context, kubeClient client.Client, namespace string, node *Node) error {
+	var obj client.Object
+	switch node.Kind {
+	case "Service":
+		obj = &v1.Service{}
+	case "Secret":
+		obj = &v1.Secret{}
+	case "StatefulSet":
+		obj = &appsv1.StatefulSet{}
+	case "Deployment":
+		obj = &appsv1.Deployment{}
+	default:
+		return fmt.Errorf("unknown kind %s", node.Kind)
+	}
+	if err := kubeClient.Get(ctx, object.NamespacedName(node.Name, namespace), obj); err != nil {
+		if errors.IsNotFound(err) {
+			node.State = Missing
+			return nil
+		}
+		return err
+	}
+	created := obj.GetCreationTimestamp()
+	node.Created = &created
+	node.State = Ready
+	switch o := obj.(type) {
+	case *v1.Service:
+		if o.Spec.Type == v1.ServiceTypeLoadBalancer && len(o.Status.LoadBalancer.Ingress) == 0 {
+			node.State = Pending
+		}
+	case *appsv1.StatefulSet:
+		if o.Spec.Replicas == nil || o.Status.ReadyReplicas < *o.Spec.Replicas {
+			node.State = Pending
+		}
+	case *appsv1.Deployment:
+		if o.Spec.Replicas == nil || o.Status.ReadyReplicas < *o.Spec.Replicas {
+			node.State = Pending
+		}
+		for _, condition := range o.Status.Conditions {
+			if node.State == Ready && condition.Type == appsv1.DeploymentAvailable && condition.Status == v1.ConditionTrue {
+				node.ReadyAfter = &metav1.Duration{Duration: condition.LastTransitionTime.Sub(created.Time)}
+			}
+		}
+	}
+	return nil
+}
+
+// DOT renders the graph in the graphviz format, nodes are colored by state
+func (g *Graph) DOT() string {
+	colors := map[State]string{Missing: "red", Pending: "orange", Ready: "green"}
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.Namespace+"/"+g.Cluster)
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q, color=%s];\n", node.ID(), fmt.Sprintf("%s\n%s", node.ID(), node.State), colors[node.State])
+	}
+	for _, node := range g.Nodes {
+		for _, dependency := range node.DependsOn {
+			fmt.Fprintf(&b, "  %q -> %q;\n", dependency, node.ID())
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Text renders the conditions of the control plane and the timeline of its
+// objects as tables, e.g.
+//
+//	OBJECT                        STATE    CREATED  READY AFTER  WAITING ON
+//	Deployment/example-apiserver  Pending  5m0s     -            StatefulSet/example-etcd
+func (g *Graph) Text(now time.Time) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONDITION\tSTATUS\tSINCE\tREASON\tMESSAGE")
+	for _, condition := range g.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status,
+			since(now, condition.LastTransitionTime.Inner.Time), condition.Reason, condition.Message)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "OBJECT\tSTATE\tCREATED\tREADY AFTER\tWAITING ON")
+	for _, node := range g.Nodes {
+		created, readyAfter := "-", "-"
+		if node.Created != nil {
+			created = since(now, node.Created.Time)
+		}
+		if node.ReadyAfter != nil {
+			readyAfter = node.ReadyAfter.Duration.Round(time.Second).String()
+		}
+		waitingOn := "-"
+		if len(node.WaitingOn) > 0 {
+			waitingOn = strings.Join(node.WaitingOn, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", node.ID(), node.State, created, readyAfter, waitingOn)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+func since(now, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return now.Sub(t).Round(time.Second).String()
+}
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Path the handler is served on, requests are of the form
+// /graph/<namespace>/<name>?format=dot|json|text
+const Path = "/graph/"
+
+// Handler serves the dependency graph of a ControlPlane
+type Handler struct {
+	kubeClient client.Client
+}
+
+func NewHandler(kubeClient client.Client) *Handler {
+	return &Handler{kubeClient: kubeClient}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, fmt.Sprintf("expected %s<namespace>/<name>", Path), http.StatusBadRequest)
+		return
+	}
+	controlPlane := &v1alpha1.ControlPlane{}
+	if err := h.kubeClient.Get(r.Context(), object.NamespacedName(parts[1], parts[0]), controlPlane); err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	graph, err := For(r.Context(), h.kubeClient, controlPlane)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(graph)
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		_, _ = w.Write([]byte(graph.DOT()))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(graph.Text(time.Now())))
+	default:
+		http.Error(w, fmt.Sprintf("unsupported format %q, expected dot, json or text", format), http.StatusBadRequest)
+	}
+}
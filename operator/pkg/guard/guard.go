@@ -0,0 +1,132 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package guard
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+const (
+	// ManagedTagKey is set to true on the AWS resources provisioned for the
+	// control planes, the guard policy denies changes to resources with it.
+	ManagedTagKey = "kit.aws/managed"
+	// ClusterTagKey is set to the namespace/name of the control plane.
+	ClusterTagKey = "kit.aws/cluster"
+)
+
+// TagsFor returns the tags of the AWS resources provisioned for a control plane
+func TagsFor(controlPlane *v1alpha1.ControlPlane) map[string]string {
+	return map[string]string{
+		ManagedTagKey: "true",
+		ClusterTagKey: controlPlane.Namespace + "/" + controlPlane.Name,
+	}
+}
+
+// Detector finds changes made outside the operator to the tags of the AWS
+// resources of a control plane
+type Detector interface {
+	Tampered(context.Context, *v1alpha1.ControlPlane) ([]string, error)
+}
+
+// AWS checks the tags of the apiserver load balancer, the only AWS resource
+// provisioned for a control plane. It's provisioned by the load balancer
+// controller of the management cluster for the endpoint Service, which adds
+// the tags back only the next time it reconciles the Service.
+type AWS struct {
+	elbv2 elbv2iface.ELBV2API
+	// arns has the ARN of the load balancer of each endpoint hostname
+	arns sync.Map
+}
+
+func NewAWS(session client.ConfigProvider) *AWS {
+	return &AWS{elbv2: elbv2.New(session)}
+}
+
+// Tampered returns the tags of the load balancer which were removed or changed,
+// e.g. kit.aws/managed removed
+func (a *AWS) Tampered(ctx context.Context, controlPlane *v1alpha1.ControlPlane) ([]string, error) {
+	endpoint, err := url.Parse(controlPlane.Status.Endpoint)
+	if err != nil || endpoint.Hostname() == "" {
+		return nil, nil
+	}
+	arn, err := a.loadBalancerARN(ctx, endpoint.Hostname())
+	if err != nil || arn == "" {
+		return nil, err
+	}
+	output, err := a.elbv2.DescribeTagsWithContext(ctx, &elbv2.DescribeTagsInput{ResourceArns: aws.StringSlice([]string{arn})})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == elbv2.ErrCodeLoadBalancerNotFoundException {
+			a.arns.Delete(endpoint.Hostname())
+			return nil, nil
+		}
+		return nil, fmt.Errorf("describing tags of load balancer %s, %w", arn, err)
+	}
+	tags := map[string]string{}
+	for _, description := range output.TagDescriptions {
+		for _, tag := range description.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+	return changes(TagsFor(controlPlane), tags), nil
+}
+
+// loadBalancerARN finds the load balancer by its DNS name, or returns an empty
+// string when it doesn't exist
+func (a *AWS) loadBalancerARN(ctx context.Context, hostname string) (string, error) {
+	if arn, ok := a.arns.Load(hostname); ok {
+		return arn.(string), nil
+	}
+	var arn string
+	if err := a.elbv2.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{}, func(output *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, loadBalancer := range output.LoadBalancers {
+			if strings.EqualFold(aws.StringValue(loadBalancer.DNSName), hostname) {
+				arn = aws.StringValue(loadBalancer.LoadBalancerArn)
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return "", fmt.Errorf("describing load balancers, %w", err)
+	}
+	if arn != "" {
+		a.arns.Store(hostname, arn)
+	}
+	return arn, nil
+}
+
+func changes(expected, actual map[string]string) []string {
+	var changed []string
+	for key, value := range expected {
+		if actualValue, ok := actual[key]; !ok {
+			changed = append(changed, fmt.Sprintf("%s removed", key))
+		} else if actualValue != value {
+			changed = append(changed, fmt.Sprintf("%s changed to %q", key, actualValue))
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,80 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// guardedActions change or delete the load balancers, listeners and target
+// groups, including the tags the policy relies on
+var guardedActions = []string{
+	"elasticloadbalancing:AddTags",
+	"elasticloadbalancing:DeleteListener",
+	"elasticloadbalancing:DeleteLoadBalancer",
+	"elasticloadbalancing:DeleteTargetGroup",
+	"elasticloadbalancing:DeregisterTargets",
+	"elasticloadbalancing:ModifyListener",
+	"elasticloadbalancing:ModifyLoadBalancerAttributes",
+	"elasticloadbalancing:ModifyTargetGroup",
+	"elasticloadbalancing:ModifyTargetGroupAttributes",
+	"elasticloadbalancing:RegisterTargets",
+	"elasticloadbalancing:RemoveTags",
+	"elasticloadbalancing:SetIpAddressType",
+	"elasticloadbalancing:SetSubnets",
+}
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+type policyStatement struct {
+	Sid       string
+	Effect    string
+	Action    []string
+	Resource  string
+	Condition map[string]map[string]interface{}
+}
+
+// Policy returns a policy denying changes to the resources tagged with
+// kit.aws/managed=true to every principal but the allowed ones. The allowed
+// principals are the roles which provision the resources, e.g. the role of the
+// load balancer controller of the management cluster, and may have wildcards.
+// The policy can be attached to the users and roles of a shared account as a
+// permissions boundary or to the account as a service control policy.
+func Policy(allowedPrincipals []string) ([]byte, error) {
+	if len(allowedPrincipals) == 0 {
+		return nil, fmt.Errorf("no principals allowed, the load balancer controller couldn't manage the load balancers")
+	}
+	document, err := json.MarshalIndent(policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{{
+			Sid:      "DenyChangesToKITManagedResources",
+			Effect:   "Deny",
+			Action:   guardedActions,
+			Resource: "*",
+			Condition: map[string]map[string]interface{}{
+				"StringEquals": {"aws:ResourceTag/" + ManagedTagKey: "true"},
+				"ArnNotLike":   {"aws:PrincipalArn": allowedPrincipals},
+			},
+		}},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding policy, %w", err)
+	}
+	return document, nil
+}
@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	"github.com/awslabs/kit/operator/pkg/utils/secrets"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Checks of a probe, in the order they run
+const (
+	CheckKubeConfig = "kubeconfig"
+	CheckReadyz     = "readyz"
+	CheckList       = "list"
+	CheckNodes      = "nodes"
+)
+
+const probeTimeout = 5 * time.Second
+
+// nodeCountInterval is how often the nodes of a cluster are counted, listing
+// all the nodes is expensive in large clusters
+const nodeCountInterval = 5 * time.Minute
+
+// Prober checks the apiserver of a guest cluster serves requests
+type Prober interface {
+	Probe(context.Context, *v1alpha1.ControlPlane) (*Result, error)
+	// Forget drops what the prober keeps of a deleted cluster
+	Forget(*v1alpha1.ControlPlane)
+}
+
+// Result of a probe which passed all the checks
+type Result struct {
+	Nodes      int32
+	ReadyNodes int32
+}
+
+// ProbeError is returned by a probe when one of its checks fails
+type ProbeError struct {
+	Check string
+	Err   error
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("%s check failed, %v", e.Check, e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// CheckOf returns the check which failed a probe, or an empty string
+func CheckOf(err error) string {
+	probeErr := &ProbeError{}
+	if errors.As(err, &probeErr) {
+		return probeErr.Check
+	}
+	return ""
+}
+
+// Guest probes the apiserver through the control plane load balancer with the
+// admin kubeconfig, the way users reach the cluster. The pods of the control
+// plane can be running and ready while the cluster is unusable, e.g. when the
+// load balancer has no healthy targets or etcd lost quorum.
+type Guest struct {
+	kubeClient client.Client
+	// nodeCounts has the last nodeCount of each cluster
+	nodeCounts sync.Map
+}
+
+type nodeCount struct {
+	Result
+	time time.Time
+}
+
+func NewGuest(kubeClient client.Client) *Guest {
+	return &Guest{kubeClient: kubeClient}
+}
+
+// Probe checks the apiserver is ready, serves a list from etcd and counts the
+// nodes registered with the cluster every nodeCountInterval
+func (g *Guest) Probe(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (*Result, error) {
+	clientSet, err := g.clientSetFor(ctx, controlPlane)
+	if err != nil {
+		return nil, &ProbeError{Check: CheckKubeConfig, Err: err}
+	}
+	if _, err := clientSet.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err != nil {
+		return nil, &ProbeError{Check: CheckReadyz, Err: err}
+	}
+	// A limited list isn't served from the watch cache, so it reads from etcd
+	if _, err := clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		return nil, &ProbeError{Check: CheckList, Err: err}
+	}
+	key := object.NamespacedName(controlPlane.Name, controlPlane.Namespace)
+	if count, ok := g.nodeCounts.Load(key); ok && time.Since(count.(nodeCount).time) < nodeCountInterval {
+		result := count.(nodeCount).Result
+		return &result, nil
+	}
+	// The list is served from the watch cache, it doesn't read from etcd
+	nodes, err := clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return nil, &ProbeError{Check: CheckNodes, Err: err}
+	}
+	count := nodeCount{Result: Result{Nodes: int32(len(nodes.Items))}, time: time.Now()}
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				count.ReadyNodes++
+			}
+		}
+	}
+	g.nodeCounts.Store(key, count)
+	result := count.Result
+	return &result, nil
+}
+
+func (g *Guest) Forget(controlPlane *v1alpha1.ControlPlane) {
+	g.nodeCounts.Delete(object.NamespacedName(controlPlane.Name, controlPlane.Namespace))
+}
+
+func (g *Guest) clientSetFor(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (kubernetes.Interface, error) {
+	secret := &v1.Secret{}
+	if err := g.kubeClient.Get(ctx, object.NamespacedName(master.KubeAdminSecretNameFor(controlPlane.ClusterName()), controlPlane.Namespace), secret); err != nil {
+		return nil, fmt.Errorf("getting admin kubeconfig, %w", err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[secrets.SecretConfigKey])
+	if err != nil {
+		return nil, fmt.Errorf("parsing admin kubeconfig, %w", err)
+	}
+	config.Timeout = probeTimeout
+	return kubernetes.NewForConfig(config)
+}
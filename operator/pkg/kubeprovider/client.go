@@ -20,6 +20,7 @@ import (
 
 	"github.com/awslabs/kit/operator/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 type Client struct {
@@ -30,12 +31,13 @@ func New(client client.Client) *Client {
 	return &Client{client}
 }
 
-// EnsureCreate creates the object if not exist, some of the objects can't be
-// patched directly like (service, secrets). In case when patching service
-// object for example, API server returns an error that clusterIP is immutable.
-// So we ensure that it's always created and exists and is not patched. Will
-// revisit both these to define what all users can change in an existing
-// cluster.
+// FieldManager is the field manager KIT applies objects with, it owns the
+// fields KIT sets on the objects it generates.
+const FieldManager = "kit"
+
+// EnsureCreate creates the object if not exist, it's used for objects which
+// are generated once and never changed, like secrets holding certificates and
+// jobs, whose pod template is immutable.
 func (c *Client) EnsureCreate(ctx context.Context, desired client.Object) error {
 	existingObject := desired.DeepCopyObject().(client.Object)
 	if err := c.Get(ctx, client.ObjectKeyFromObject(desired), existingObject); err != nil {
@@ -48,19 +50,19 @@ func (c *Client) EnsureCreate(ctx context.Context, desired client.Object) error
 	return nil
 }
 
-// EnsurePatch creates if not exist, else will patch the existing object. Its
-// used for deployments, statefulsets to provide configurability for flags.
-func (c *Client) EnsurePatch(ctx context.Context, object, desired client.Object) error {
-	if err := c.Get(ctx, client.ObjectKeyFromObject(desired), object); err != nil {
-		if errors.IsNotFound(err) {
-			return c.Create(ctx, desired)
-		}
-		return fmt.Errorf("getting object %v, name %v, %w",
-			desired.GetObjectKind().GroupVersionKind().GroupKind().String(), desired.GetName(), err)
+// EnsureApply creates or updates the object with server side apply. Every
+// field set in desired is owned by KIT and reset to the desired value on each
+// reconcile, while fields set by other managers, like labels and annotations
+// users add to the object, are preserved.
+func (c *Client) EnsureApply(ctx context.Context, desired client.Object) error {
+	gvk, err := apiutil.GVKForObject(desired, c.Scheme())
+	if err != nil {
+		return fmt.Errorf("getting kind of %v, %w", desired.GetName(), err)
 	}
-	desired.SetResourceVersion(object.GetResourceVersion())
-	if err := c.Patch(ctx, desired, client.StrategicMergeFrom(object)); err != nil {
-		return fmt.Errorf("failed to patch, %v, %w", desired.GetName(), err)
+	// Apply patches are sent as is, so they need the type meta set.
+	desired.GetObjectKind().SetGroupVersionKind(gvk)
+	if err := c.Patch(ctx, desired, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership); err != nil {
+		return fmt.Errorf("applying %v, name %v, %w", gvk.GroupKind().String(), desired.GetName(), err)
 	}
 	return nil
 }
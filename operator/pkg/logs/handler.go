@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/controllers/etcd"
+	"github.com/awslabs/kit/operator/pkg/controllers/master"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Path the handler is served on, requests are of the form
+// /logs/<namespace>/<name>?component=apiserver&since=10m&follow=true
+const Path = "/logs/"
+
+// components maps the component names accepted in requests to the name of
+// their workload, etcd is a StatefulSet and the others are Deployments.
+var components = map[string]func(clusterName string) string{
+	"etcd":                     etcd.ServiceNameFor,
+	"apiserver":                master.APIServerDeploymentName,
+	"controller-manager":       master.KCMDeploymentName,
+	"scheduler":                master.SchedulerDeploymentName,
+	"cloud-controller-manager": master.CCMDeploymentName,
+}
+
+// Handler streams the logs of the control plane components of a cluster, the
+// lines of every container are merged and prefixed with pod/container so the
+// generated pod names don't have to be looked up.
+type Handler struct {
+	clientSet kubernetes.Interface
+}
+
+func NewHandler(clientSet kubernetes.Interface) *Handler {
+	return &Handler{clientSet: clientSet}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Path), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, fmt.Sprintf("expected %s<namespace>/<name>", Path), http.StatusBadRequest)
+		return
+	}
+	namespace, name := parts[0], parts[1]
+	var names []string
+	if component := r.URL.Query().Get("component"); component != "" {
+		if _, ok := components[component]; !ok {
+			http.Error(w, fmt.Sprintf("unknown component %q, expected one of %s", component, strings.Join(componentNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		names = []string{component}
+	}
+	options := &v1.PodLogOptions{Follow: r.URL.Query().Get("follow") == "true"}
+	if since := r.URL.Query().Get("since"); since != "" {
+		duration, err := time.ParseDuration(since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("parsing since, %v", err), http.StatusBadRequest)
+			return
+		}
+		options.SinceSeconds = aws.Int64(int64(duration.Seconds()))
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := h.Stream(r.Context(), w, namespace, name, names, options); err != nil {
+		if errors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Stream writes the logs of the components of a cluster to w, all of them when
+// no components are given. The pods are looked up before anything is written,
+// so an error is returned before the logs are streamed.
+func (h *Handler) Stream(ctx context.Context, w io.Writer, namespace, name string, names []string, options *v1.PodLogOptions) error {
+	all := len(names) == 0
+	if all {
+		names = componentNames()
+	}
+	var pods []v1.Pod
+	for _, component := range names {
+		componentPods, err := h.podsFor(ctx, namespace, components[component](name), component == "etcd")
+		if err != nil {
+			// Components which aren't enabled on the cluster are skipped
+			// unless they were asked for
+			if errors.IsNotFound(err) && all {
+				continue
+			}
+			return err
+		}
+		pods = append(pods, componentPods...)
+	}
+	h.stream(ctx, w, pods, options)
+	return nil
+}
+
+// podsFor lists the pods selected by the workload of a component
+func (h *Handler) podsFor(ctx context.Context, namespace, workload string, statefulSet bool) ([]v1.Pod, error) {
+	var selector *metav1.LabelSelector
+	if statefulSet {
+		set, err := h.clientSet.AppsV1().StatefulSets(namespace).Get(ctx, workload, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting statefulset %s, %w", workload, err)
+		}
+		selector = set.Spec.Selector
+	} else {
+		deployment, err := h.clientSet.AppsV1().Deployments(namespace).Get(ctx, workload, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting deployment %s, %w", workload, err)
+		}
+		selector = deployment.Spec.Selector
+	}
+	pods, err := h.clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: metav1.FormatLabelSelector(selector)})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods of %s, %w", workload, err)
+	}
+	return pods.Items, nil
+}
+
+// stream copies the logs of every container of the pods to w as lines are
+// read, until all the streams end or the request is cancelled
+func (h *Handler) stream(ctx context.Context, w io.Writer, pods []v1.Pod, options *v1.PodLogOptions) {
+	flusher, _ := w.(http.Flusher)
+	mu := sync.Mutex{}
+	write := func(line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = fmt.Fprintln(w, line)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	wg := sync.WaitGroup{}
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			wg.Add(1)
+			go func(pod v1.Pod, container string) {
+				defer wg.Done()
+				prefix := fmt.Sprintf("[%s/%s]", pod.Name, container)
+				containerOptions := options.DeepCopy()
+				containerOptions.Container = container
+				logs, err := h.clientSet.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, containerOptions).Stream(ctx)
+				if err != nil {
+					write(fmt.Sprintf("%s getting logs, %v", prefix, err))
+					return
+				}
+				defer logs.Close()
+				scanner := bufio.NewScanner(logs)
+				for scanner.Scan() {
+					write(prefix + " " + scanner.Text())
+				}
+			}(pod, container.Name)
+		}
+	}
+	wg.Wait()
+}
+
+func componentNames() []string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+)
+
+// EventBridge puts events on an EventBridge bus, the event type is used as
+// the detail type so rules can match on it.
+type EventBridge struct {
+	bus    string
+	client eventbridgeiface.EventBridgeAPI
+}
+
+func NewEventBridge(session client.ConfigProvider, bus string) *EventBridge {
+	return &EventBridge{bus: bus, client: eventbridge.New(session)}
+}
+
+func (e *EventBridge) Publish(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event, %w", err)
+	}
+	output, err := e.client.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{{
+			EventBusName: aws.String(e.bus),
+			Source:       aws.String(Source),
+			DetailType:   aws.String(string(event.Type)),
+			Detail:       aws.String(string(detail)),
+			Time:         aws.Time(event.Time),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("putting event on bus %s, %w", e.bus, err)
+	}
+	if aws.Int64Value(output.FailedEntryCount) > 0 {
+		return fmt.Errorf("putting event on bus %s, %s", e.bus, aws.StringValue(output.Entries[0].ErrorMessage))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"go.uber.org/multierr"
+)
+
+const (
+	// Source identifies events published by KIT
+	Source = "kit.k8s.sh"
+	// SchemaVersion is bumped on breaking changes to the Event JSON
+	SchemaVersion = "v1"
+)
+
+// EventType is a step in the lifecycle of a cluster
+type EventType string
+
+const (
+	Provisioned EventType = "Provisioned"
+	Upgraded    EventType = "Upgraded"
+	Failed      EventType = "Failed"
+	Deleted     EventType = "Deleted"
+	// DeletionStuck is published when a cluster is still being deleted after
+	// the stuck deletion timeout, e.g. waiting on a finalizer of another
+	// controller.
+	DeletionStuck EventType = "DeletionStuck"
+)
+
+// Event is the JSON document published for every cluster lifecycle event
+type Event struct {
+	Version           string    `json:"version"`
+	Type              EventType `json:"type"`
+	Cluster           string    `json:"cluster"`
+	Namespace         string    `json:"namespace"`
+	KubernetesVersion string    `json:"kubernetesVersion,omitempty"`
+	Message           string    `json:"message,omitempty"`
+	Time              time.Time `json:"time"`
+}
+
+// Publisher sends cluster lifecycle events to external automation
+type Publisher interface {
+	Publish(context.Context, Event) error
+}
+
+// NewEvent returns an event of the given type for the control plane
+func NewEvent(eventType EventType, controlPlane *v1alpha1.ControlPlane, message string) Event {
+	return Event{
+		Version:           SchemaVersion,
+		Type:              eventType,
+		Cluster:           controlPlane.ClusterName(),
+		Namespace:         controlPlane.Namespace,
+		KubernetesVersion: controlPlane.Spec.KubernetesVersion,
+		Message:           message,
+		Time:              time.Now().UTC(),
+	}
+}
+
+// Publishers sends every event to all of the publishers, a failing publisher
+// doesn't stop the event from reaching the others.
+type Publishers []Publisher
+
+func (p Publishers) Publish(ctx context.Context, event Event) (errs error) {
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+	return errs
+}
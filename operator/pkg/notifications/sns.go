@@ -0,0 +1,54 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// SNS publishes events to a topic, the event type is set as a message
+// attribute so that subscriptions can filter on it.
+type SNS struct {
+	topicARN string
+	client   snsiface.SNSAPI
+}
+
+func NewSNS(session client.ConfigProvider, topicARN string) *SNS {
+	return &SNS{topicARN: topicARN, client: sns.New(session)}
+}
+
+func (s *SNS) Publish(ctx context.Context, event Event) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshalling event, %w", err)
+	}
+	if _, err := s.client.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(s.topicARN),
+		Message:  aws.String(string(message)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"type": {DataType: aws.String("String"), StringValue: aws.String(string(event.Type))},
+		},
+	}); err != nil {
+		return fmt.Errorf("publishing event to topic %s, %w", s.topicARN, err)
+	}
+	return nil
+}
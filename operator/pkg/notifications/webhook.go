@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Webhook posts a Slack compatible message for the events cluster owners need
+// to act on, i.e. failures and stuck deletions. Events are routed to the URL
+// of the cluster's namespace, falling back to the default URL.
+type Webhook struct {
+	defaultURL string
+	routes     map[string]string
+	client     *http.Client
+}
+
+func NewWebhook(defaultURL string, routes map[string]string) *Webhook {
+	return &Webhook{defaultURL: defaultURL, routes: routes, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ParseRoutes parses comma separated namespace=url pairs
+func ParseRoutes(routes string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, route := range strings.Split(routes, ",") {
+		if route = strings.TrimSpace(route); route == "" {
+			continue
+		}
+		parts := strings.SplitN(route, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid route %q, expected namespace=url", route)
+		}
+		parsed[parts[0]] = parts[1]
+	}
+	return parsed, nil
+}
+
+func (w *Webhook) Publish(ctx context.Context, event Event) error {
+	if event.Type != Failed && event.Type != DeletionStuck {
+		return nil
+	}
+	url := w.urlFor(event.Namespace)
+	if url == "" {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]string{"text": textFor(event)})
+	if err != nil {
+		return fmt.Errorf("marshalling webhook payload, %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating webhook request, %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := w.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("posting to webhook, %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("posting to webhook, got status %s", response.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) urlFor(namespace string) string {
+	if url, ok := w.routes[namespace]; ok {
+		return url
+	}
+	return w.defaultURL
+}
+
+func textFor(event Event) string {
+	switch event.Type {
+	case DeletionStuck:
+		return fmt.Sprintf(":warning: Deletion of cluster %s/%s is stuck, %s", event.Namespace, event.Cluster, event.Message)
+	default:
+		return fmt.Sprintf(":x: Cluster %s/%s failed to reconcile, %s", event.Namespace, event.Cluster, event.Message)
+	}
+}
@@ -72,6 +72,15 @@ func GenerateSignedCertAndKey(config *certutil.Config, caCertBytes, caKeyBytes [
 	return encodePrivateKey(key), encodeCertificate(cert), nil
 }
 
+// HasDNSNames returns true if the certificate is valid for all the names
+func HasDNSNames(certBytes []byte, names []string) (bool, error) {
+	certs, err := certutil.ParseCertsPEM(certBytes)
+	if err != nil {
+		return false, fmt.Errorf("parsing cert, %w", err)
+	}
+	return sets.NewString(certs[0].DNSNames...).HasAll(names...), nil
+}
+
 func GenerateKeyPair() (private, public []byte, err error) {
 	key, err := rsa.GenerateKey(cryptorand.Reader, rsaKeySize)
 	if err != nil {
@@ -0,0 +1,222 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+const (
+	// StandardOnDemandVCPUs is the Running On-Demand Standard (A, C, D, H, I,
+	// M, R, T, Z) instances quota, in vCPUs
+	StandardOnDemandVCPUs = "L-1216C47A"
+	serviceCode           = "ec2"
+	masterInstances       = 3
+	etcdInstances         = 3
+)
+
+// Checker checks the account has the quota to provision a control plane
+type Checker interface {
+	Check(context.Context, *v1alpha1.ControlPlane) error
+}
+
+// InsufficientError is returned when provisioning a control plane would
+// exceed a quota of the account, RequestID is set when an increase of the
+// quota has been requested.
+type InsufficientError struct {
+	Code      string
+	Required  float64
+	Usage     float64
+	Limit     float64
+	RequestID string
+}
+
+func (e *InsufficientError) Error() string {
+	message := fmt.Sprintf("insufficient quota %s, %.0f required with %.0f of %.0f in use",
+		e.Code, e.Required, e.Usage, e.Limit)
+	if e.RequestID != "" {
+		message += fmt.Sprintf(", waiting on increase request %s", e.RequestID)
+	}
+	return message
+}
+
+func IsInsufficient(err error) bool {
+	insufficient := &InsufficientError{}
+	return errors.As(err, &insufficient)
+}
+
+// IsWaitingOnIncrease returns true if the quota is insufficient and an
+// increase has been requested
+func IsWaitingOnIncrease(err error) bool {
+	insufficient := &InsufficientError{}
+	return errors.As(err, &insufficient) && insufficient.RequestID != ""
+}
+
+// ServiceQuotas compares the on-demand vCPUs of the control plane instances
+// and the vCPUs of the running instances to the quota of the account. Karpenter
+// launches the instances, so they only count once the nodes are up and the
+// check is only meaningful before the first pods of a cluster are created.
+type ServiceQuotas struct {
+	ec2    ec2iface.EC2API
+	quotas servicequotasiface.ServiceQuotasAPI
+	// requestIncreases files a request for the missing quota when a check
+	// fails, unless one is already open
+	requestIncreases bool
+}
+
+func NewServiceQuotas(session client.ConfigProvider, requestIncreases bool) *ServiceQuotas {
+	return &ServiceQuotas{ec2: ec2.New(session), quotas: servicequotas.New(session), requestIncreases: requestIncreases}
+}
+
+func (s *ServiceQuotas) Check(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	required, err := s.required(ctx, controlPlane)
+	if err != nil {
+		return err
+	}
+	if required == 0 {
+		return nil
+	}
+	output, err := s.quotas.GetServiceQuotaWithContext(ctx, &servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(StandardOnDemandVCPUs),
+	})
+	if err != nil {
+		return fmt.Errorf("getting quota %s, %w", StandardOnDemandVCPUs, err)
+	}
+	limit := aws.Float64Value(output.Quota.Value)
+	usage, err := s.usage(ctx)
+	if err != nil {
+		return err
+	}
+	if usage+required <= limit {
+		return nil
+	}
+	insufficient := &InsufficientError{Code: StandardOnDemandVCPUs, Required: required, Usage: usage, Limit: limit}
+	if s.requestIncreases {
+		if insufficient.RequestID, err = s.requestIncrease(ctx, StandardOnDemandVCPUs, usage+required); err != nil {
+			return err
+		}
+	}
+	return insufficient
+}
+
+// requestIncrease returns the ID of an open request for at least the desired
+// value, or requests an increase to it. Requests are per account, so clusters
+// provisioned together share the request of the first one.
+func (s *ServiceQuotas) requestIncrease(ctx context.Context, code string, desired float64) (string, error) {
+	var open string
+	if err := s.quotas.ListRequestedServiceQuotaChangeHistoryByQuotaPagesWithContext(ctx, &servicequotas.ListRequestedServiceQuotaChangeHistoryByQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(code),
+	}, func(output *servicequotas.ListRequestedServiceQuotaChangeHistoryByQuotaOutput, _ bool) bool {
+		for _, request := range output.RequestedQuotas {
+			status := aws.StringValue(request.Status)
+			if (status == servicequotas.RequestStatusPending || status == servicequotas.RequestStatusCaseOpened) &&
+				aws.Float64Value(request.DesiredValue) >= desired {
+				open = aws.StringValue(request.Id)
+				return false
+			}
+		}
+		return true
+	}); err != nil {
+		return "", fmt.Errorf("listing requests for quota %s, %w", code, err)
+	}
+	if open != "" {
+		return open, nil
+	}
+	output, err := s.quotas.RequestServiceQuotaIncreaseWithContext(ctx, &servicequotas.RequestServiceQuotaIncreaseInput{
+		ServiceCode:  aws.String(serviceCode),
+		QuotaCode:    aws.String(code),
+		DesiredValue: aws.Float64(desired),
+	})
+	if err != nil {
+		return "", fmt.Errorf("requesting an increase of quota %s to %.0f, %w", code, desired, err)
+	}
+	return aws.StringValue(output.RequestedQuota.Id), nil
+}
+
+// required returns the vCPUs of the master and etcd instances, instance types
+// which aren't set are picked by Karpenter and can't be accounted for.
+func (s *ServiceQuotas) required(ctx context.Context, controlPlane *v1alpha1.ControlPlane) (float64, error) {
+	instances := map[string]int64{}
+	if !controlPlane.Spec.Paused && isStandard(controlPlane.Spec.Master.Type) {
+		instances[controlPlane.Spec.Master.Type] += masterInstances
+	}
+	if isStandard(controlPlane.Spec.Etcd.Type) {
+		instances[controlPlane.Spec.Etcd.Type] += etcdInstances
+	}
+	if len(instances) == 0 {
+		return 0, nil
+	}
+	input := &ec2.DescribeInstanceTypesInput{}
+	for instanceType := range instances {
+		input.InstanceTypes = append(input.InstanceTypes, aws.String(instanceType))
+	}
+	output, err := s.ec2.DescribeInstanceTypesWithContext(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("describing instance types, %w", err)
+	}
+	var vCPUs float64
+	for _, instanceType := range output.InstanceTypes {
+		vCPUs += float64(instances[aws.StringValue(instanceType.InstanceType)] * aws.Int64Value(instanceType.VCpuInfo.DefaultVCpus))
+	}
+	return vCPUs, nil
+}
+
+// usage returns the vCPUs of the pending and running on-demand instances
+// counted against the quota
+func (s *ServiceQuotas) usage(ctx context.Context) (vCPUs float64, err error) {
+	if err := s.ec2.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running"})}},
+	}, func(output *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range output.Reservations {
+			for _, instance := range reservation.Instances {
+				if aws.StringValue(instance.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot || !isStandard(aws.StringValue(instance.InstanceType)) {
+					continue
+				}
+				if instance.CpuOptions != nil {
+					vCPUs += float64(aws.Int64Value(instance.CpuOptions.CoreCount) * aws.Int64Value(instance.CpuOptions.ThreadsPerCore))
+				}
+			}
+		}
+		return true
+	}); err != nil {
+		return 0, fmt.Errorf("describing instances, %w", err)
+	}
+	return vCPUs, nil
+}
+
+// standardFamily matches the families of the standard quota, e.g. m5 or
+// r6gd. Families starting with the same letters have quotas of their own,
+// e.g. hpc6a, inf1, trn1, mac1 and u-6tb1.
+var standardFamily = regexp.MustCompile(`^(a1|c[0-9][a-z]*|d[0-9][a-z]*|h1|i[0-9][a-z]*|im4gn|is4gen|m[0-9][a-z-]*|r[0-9][a-z]*|t[0-9][a-z]*|z1d)$`)
+
+// isStandard returns true for the instance types of the standard families,
+// e.g. m5.large, but not inf1.xlarge which has a quota of its own
+func isStandard(instanceType string) bool {
+	return standardFamily.MatchString(strings.SplitN(instanceType, ".", 2)[0])
+}
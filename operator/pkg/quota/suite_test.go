@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func TestAPIs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quota")
+}
+
+var _ = Describe("Standard", func() {
+	DescribeTable("should only count the families of the standard quota",
+		func(instanceType string, standard bool) {
+			Expect(isStandard(instanceType)).To(Equal(standard))
+		},
+		Entry("general purpose", "m5.large", true),
+		Entry("graviton", "m6g.xlarge", true),
+		Entry("flex", "m7i-flex.large", true),
+		Entry("burstable", "t3.medium", true),
+		Entry("compute optimized", "c5n.2xlarge", true),
+		Entry("memory optimized", "r5d.large", true),
+		Entry("storage optimized", "i3en.large", true),
+		Entry("dense storage", "d3en.xlarge", true),
+		Entry("high frequency", "z1d.large", true),
+		Entry("arm", "a1.medium", true),
+		Entry("hdd storage", "h1.2xlarge", true),
+		Entry("unset", "", false),
+		Entry("inferentia", "inf1.xlarge", false),
+		Entry("trainium", "trn1.32xlarge", false),
+		Entry("hpc", "hpc6a.48xlarge", false),
+		Entry("high memory", "u-6tb1.metal", false),
+		Entry("mac", "mac1.metal", false),
+		Entry("habana", "dl1.24xlarge", false),
+		Entry("gpu", "p3.2xlarge", false),
+	)
+})
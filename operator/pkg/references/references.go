@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package references
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// Validator checks the AWS resources a control plane refers to exist
+type Validator interface {
+	Validate(context.Context, *v1alpha1.ControlPlane) error
+}
+
+// InvalidError is returned when a field refers to an AWS resource which
+// doesn't exist or can't be used, Reason says what to fix.
+type InvalidError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("invalid %s %q, %s", e.Field, e.Value, e.Reason)
+}
+
+func IsInvalid(err error) bool {
+	invalid := &InvalidError{}
+	return errors.As(err, &invalid)
+}
+
+// metadata is the instance metadata of the node the operator runs on
+type metadata interface {
+	GetMetadataWithContext(context.Context, string) (string, error)
+}
+
+// AWS validates the instance types and the artifact bucket of a control plane
+// against the account and region of the operator, and its CIDRs against the
+// VPC of the operator which the nodes of the clusters are launched in.
+type AWS struct {
+	ec2      ec2iface.EC2API
+	s3       s3iface.S3API
+	metadata metadata
+}
+
+func NewAWS(session client.ConfigProvider) *AWS {
+	return &AWS{ec2: ec2.New(session), s3: s3.New(session), metadata: ec2metadata.New(session)}
+}
+
+func (a *AWS) Validate(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	for field, instanceType := range map[string]string{
+		"spec.master.type": controlPlane.Spec.Master.Type,
+		"spec.etcd.type":   controlPlane.Spec.Etcd.Type,
+	} {
+		if err := a.validateInstanceType(ctx, field, instanceType); err != nil {
+			return err
+		}
+	}
+	if err := a.validateNetwork(ctx, controlPlane); err != nil {
+		return err
+	}
+	return a.validateBucket(ctx, controlPlane.Spec.ArtifactBucket)
+}
+
+// validateNetwork rejects pod and service CIDRs overlapping the VPC, the
+// addresses of the nodes and of the VPC CNI pods would be routed to services.
+// The default service CIDR is checked when the cluster doesn't set one.
+func (a *AWS) validateNetwork(ctx context.Context, controlPlane *v1alpha1.ControlPlane) error {
+	vpcCIDRs, err := a.vpcCIDRs(ctx)
+	if err != nil {
+		return err
+	}
+	for field, cidr := range map[string]string{
+		"spec.network.podCIDR":     controlPlane.PodCIDR(),
+		"spec.network.serviceCIDR": controlPlane.ServiceCIDR(),
+	} {
+		if cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("parsing %s, %w", field, err)
+		}
+		for _, vpcCIDR := range vpcCIDRs {
+			if v1alpha1.Overlap(ipNet, vpcCIDR) {
+				return &InvalidError{Field: field, Value: cidr, Reason: fmt.Sprintf("it overlaps the VPC CIDR %s, set %s to a range outside of the VPC", vpcCIDR, field)}
+			}
+		}
+	}
+	return nil
+}
+
+// VPCCIDRs returns the IPv4 CIDRs of the VPC of the node the operator runs on
+func VPCCIDRs(ctx context.Context, session client.ConfigProvider) ([]string, error) {
+	cidrs, err := (&AWS{metadata: ec2metadata.New(session)}).vpcCIDRs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []string
+	for _, cidr := range cidrs {
+		blocks = append(blocks, cidr.String())
+	}
+	return blocks, nil
+}
+
+// vpcCIDRs returns the IPv4 CIDRs of the VPC of the node the operator runs on
+func (a *AWS) vpcCIDRs(ctx context.Context) ([]*net.IPNet, error) {
+	mac, err := a.metadata.GetMetadataWithContext(ctx, "mac")
+	if err != nil {
+		return nil, fmt.Errorf("getting mac address from instance metadata, %w", err)
+	}
+	blocks, err := a.metadata.GetMetadataWithContext(ctx, fmt.Sprintf("network/interfaces/macs/%s/vpc-ipv4-cidr-blocks", mac))
+	if err != nil {
+		return nil, fmt.Errorf("getting VPC CIDRs from instance metadata, %w", err)
+	}
+	var cidrs []*net.IPNet
+	for _, block := range strings.Fields(blocks) {
+		_, cidr, err := net.ParseCIDR(block)
+		if err != nil {
+			return nil, fmt.Errorf("parsing VPC CIDR %s, %w", block, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+func (a *AWS) validateInstanceType(ctx context.Context, field, instanceType string) error {
+	if instanceType == "" {
+		return nil
+	}
+	if _, err := a.ec2.DescribeInstanceTypesWithContext(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: aws.StringSlice([]string{instanceType}),
+	}); err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvalidInstanceType" {
+			return &InvalidError{Field: field, Value: instanceType, Reason: "the instance type isn't offered in this region"}
+		}
+		return fmt.Errorf("describing instance type %s, %w", instanceType, err)
+	}
+	return nil
+}
+
+func (a *AWS) validateBucket(ctx context.Context, artifactBucket string) error {
+	if artifactBucket == "" {
+		return nil
+	}
+	bucket := strings.SplitN(artifactBucket, "/", 2)[0]
+	if _, err := a.s3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		if failure, ok := err.(awserr.RequestFailure); ok {
+			switch failure.StatusCode() {
+			case http.StatusNotFound:
+				return &InvalidError{Field: "spec.artifactBucket", Value: artifactBucket, Reason: fmt.Sprintf("bucket %s doesn't exist", bucket)}
+			case http.StatusForbidden:
+				return &InvalidError{Field: "spec.artifactBucket", Value: artifactBucket, Reason: fmt.Sprintf("bucket %s isn't accessible to KIT", bucket)}
+			}
+		}
+		return fmt.Errorf("getting bucket %s, %w", bucket, err)
+	}
+	return nil
+}
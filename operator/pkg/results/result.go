@@ -25,4 +25,7 @@ var (
 	Waiting    = &reconcile.Result{RequeueAfter: 5 * time.Second}
 	Created    = &reconcile.Result{RequeueAfter: 60 * time.Second}
 	Terminated = &reconcile.Result{}
+	// WaitingOnQuota checks a requested quota increase again, they take
+	// minutes to days to be approved
+	WaitingOnQuota = &reconcile.Result{RequeueAfter: 5 * time.Minute}
 )
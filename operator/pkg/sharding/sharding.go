@@ -0,0 +1,204 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	leaseDuration = 30 * time.Second
+	renewInterval = 10 * time.Second
+)
+
+// MemberLabelKey labels the Lease every replica of the operator holds to be a
+// member of the shards.
+var MemberLabelKey = v1alpha1.SchemeGroupVersion.Group + "/shard-member"
+
+// Membership spreads resources over the replicas of the operator. Every
+// replica renews a Lease of its own, the replicas with an unexpired Lease are
+// the members and a resource belongs to the member with the highest hash of
+// its name and the resource's namespace and name. Only the resources of a
+// replica which joins or leaves move to other members. A replica which can't
+// renew its Lease stops reconciling before the other members see it expired,
+// and a member only reconciles the resources it gets from another member one
+// lease duration after the members changed, once the previous owner stopped.
+// Leases are owned by the pod of their replica and deleted when it stops, so
+// restarted pods don't leave Leases behind. Leases are read from the
+// apiserver, not to cache the Leases of the whole cluster.
+type Membership struct {
+	kubeClient client.Client
+	apiReader  client.Reader
+	namespace  string
+	identity   string
+
+	mu      sync.RWMutex
+	members []string
+	renewed time.Time
+	// stable are the members before the last changes, changed is when the
+	// members last changed
+	stable  []string
+	changed time.Time
+}
+
+func New(kubeClient client.Client, apiReader client.Reader, namespace, identity string) *Membership {
+	return &Membership{kubeClient: kubeClient, apiReader: apiReader, namespace: namespace, identity: identity}
+}
+
+// Start renews the Lease of this replica and refreshes the members until the
+// context is done.
+func (m *Membership) Start(ctx context.Context) error {
+	for {
+		if err := m.renew(ctx); err != nil {
+			logging.FromContext(ctx).Errorf("Renewing shard membership, %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			m.release(ctx)
+			return nil
+		case <-time.After(renewInterval):
+		}
+	}
+}
+
+// release deletes the Lease of this replica so the other members take over its
+// resources without waiting for it to expire
+func (m *Membership) release(ctx context.Context) {
+	// The context of the replica is done, the Lease is deleted with a new one
+	deleteCtx, cancel := context.WithTimeout(context.Background(), renewInterval)
+	defer cancel()
+	if err := m.kubeClient.Delete(deleteCtx, &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: m.leaseName(), Namespace: m.namespace}}); err != nil && !errors.IsNotFound(err) {
+		logging.FromContext(ctx).Errorf("Deleting shard lease, %v", err)
+	}
+}
+
+func (m *Membership) leaseName() string {
+	return fmt.Sprintf("kit-shard-%s", m.identity)
+}
+
+// NeedLeaderElection is false as every replica is a member, replicas only
+// reconcile the resources of their shard instead of electing a leader.
+func (m *Membership) NeedLeaderElection() bool {
+	return false
+}
+
+// Owns returns true if the resource belongs to this replica's shard and the
+// Lease of this replica wasn't renewed too long ago. Resources which belonged
+// to another member before the members changed are only owned one lease
+// duration after the change.
+func (m *Membership) Owns(nn types.NamespacedName) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if time.Since(m.renewed) >= leaseDuration-renewInterval {
+		return false
+	}
+	if ownerOf(m.members, nn) != m.identity {
+		return false
+	}
+	return time.Since(m.changed) >= leaseDuration || ownerOf(m.stable, nn) == m.identity
+}
+
+// ownerOf returns the member with the highest hash of its name and the
+// resource, or an empty string when there are no members
+func ownerOf(members []string, nn types.NamespacedName) string {
+	var owner string
+	var highest uint64
+	for _, member := range members {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(member + "/" + nn.String()))
+		if score := hash.Sum64(); owner == "" || score > highest {
+			owner, highest = member, score
+		}
+	}
+	return owner
+}
+
+func (m *Membership) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	nn := types.NamespacedName{Namespace: m.namespace, Name: m.leaseName()}
+	if err := m.apiReader.Get(ctx, nn, lease); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("getting lease, %w", err)
+		}
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace, Labels: map[string]string{MemberLabelKey: "true"}},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       aws.String(m.identity),
+				LeaseDurationSeconds: aws.Int32(int32(leaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		// The Lease is garbage collected with the pod when the replica
+		// doesn't delete it, e.g. when it's killed
+		pod := &v1.Pod{}
+		if err := m.apiReader.Get(ctx, types.NamespacedName{Namespace: m.namespace, Name: m.identity}, pod); err != nil {
+			return fmt.Errorf("getting pod, %w", err)
+		}
+		lease.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: pod.Name, UID: pod.UID}}
+		if err := m.kubeClient.Create(ctx, lease); err != nil {
+			return fmt.Errorf("creating lease, %w", err)
+		}
+	} else {
+		lease.Spec.RenewTime = &now
+		if err := m.kubeClient.Update(ctx, lease); err != nil {
+			return fmt.Errorf("updating lease, %w", err)
+		}
+	}
+	leases := &coordinationv1.LeaseList{}
+	if err := m.apiReader.List(ctx, leases, client.InNamespace(m.namespace), client.MatchingLabels{MemberLabelKey: "true"}); err != nil {
+		return fmt.Errorf("listing leases, %w", err)
+	}
+	members := []string{}
+	for _, lease := range leases.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		if time.Since(lease.Spec.RenewTime.Time) < time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second {
+			members = append(members, *lease.Spec.HolderIdentity)
+		}
+	}
+	sort.Strings(members)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.changed.IsZero() || fmt.Sprint(members) != fmt.Sprint(m.members) {
+		logging.FromContext(ctx).Infof("Shard members changed to %v", members)
+		// Changes within a lease duration of the last one keep the members
+		// from before it, their owners may not have stopped yet
+		if time.Since(m.changed) >= leaseDuration {
+			m.stable = m.members
+		}
+		m.changed = now.Time
+	}
+	m.members = members
+	m.renewed = now.Time
+	return nil
+}
@@ -32,7 +32,7 @@ type Environment struct {
 func New() *Environment {
 	return &Environment{
 		Environment: envtest.Environment{
-			CRDDirectoryPaths: []string{crdFilePath()},
+			CRDDirectoryPaths: crdFilePaths(),
 		},
 	}
 }
@@ -54,8 +54,13 @@ func (e *Environment) Stop() error {
 	return e.Environment.Stop()
 }
 
-func crdFilePath() string {
+func crdFilePaths() []string {
 	_, file, _, _ := runtime.Caller(0)
 	p := filepath.Join(filepath.Dir(file), "..", "..", "..")
-	return filepath.Join(p, "config/control-plane-crd.yaml")
+	return []string{
+		filepath.Join(p, "config/control-plane-crd.yaml"),
+		filepath.Join(p, "config/cluster-template-crd.yaml"),
+		filepath.Join(p, "config/cluster-set-crd.yaml"),
+		filepath.Join(p, "config/load-test-crd.yaml"),
+	}
 }
@@ -78,11 +78,31 @@ func ExpectCleanedUp(c client.Client) {
 	for _, provisioner := range controlPlanes.Items {
 		ExpectDeleted(c, &provisioner)
 	}
+	loadTests := v1alpha1.LoadTestList{}
+	Expect(c.List(ctx, &loadTests)).To(Succeed())
+	for _, loadTest := range loadTests.Items {
+		ExpectDeleted(c, &loadTest)
+	}
+	clusterSets := v1alpha1.ClusterSetList{}
+	Expect(c.List(ctx, &clusterSets)).To(Succeed())
+	for _, clusterSet := range clusterSets.Items {
+		ExpectDeleted(c, &clusterSet)
+	}
+	templates := v1alpha1.ClusterTemplateList{}
+	Expect(c.List(ctx, &templates)).To(Succeed())
+	for _, template := range templates.Items {
+		ExpectDeleted(c, &template)
+	}
 	services := v1.ServiceList{}
 	Expect(c.List(ctx, &services)).To(Succeed())
 	for _, service := range services.Items {
 		ExpectDeleted(c, &service)
 	}
+	configMaps := v1.ConfigMapList{}
+	Expect(c.List(ctx, &configMaps)).To(Succeed())
+	for _, configMap := range configMaps.Items {
+		ExpectDeleted(c, &configMap)
+	}
 	secrets := v1.SecretList{}
 	Expect(c.List(ctx, &secrets)).To(Succeed())
 	for _, secret := range secrets.Items {
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package images
+
+import (
+	"regexp"
+	"strings"
+)
+
+var manifestImage = regexp.MustCompile(`(?m)^([ \t]*-?[ \t]*image:[ \t]*)"?([^"\s]+)"?[ \t]*$`)
+
+// Mirror returns image pulled from registry instead of the registry in its
+// reference, keeping its repository, e.g. public.ecr.aws/eks-distro/etcd-io/etcd:v3.4.14
+// becomes <registry>/eks-distro/etcd-io/etcd:v3.4.14. The image is returned
+// unchanged when registry is empty.
+func Mirror(registry, image string) string {
+	if registry == "" {
+		return image
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + repositoryOf(image)
+}
+
+// MirrorManifest mirrors the image of every image field in a YAML manifest
+func MirrorManifest(registry, manifest string) string {
+	if registry == "" {
+		return manifest
+	}
+	return manifestImage.ReplaceAllStringFunc(manifest, func(line string) string {
+		match := manifestImage.FindStringSubmatch(line)
+		return match[1] + Mirror(registry, match[2])
+	})
+}
+
+// repositoryOf strips the registry from an image reference, the first part of
+// a reference is a registry when it's a hostname, otherwise the image is on
+// Docker Hub and the reference has no registry.
+func repositoryOf(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[1]
+	}
+	return image
+}
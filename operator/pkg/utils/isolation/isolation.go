@@ -0,0 +1,96 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package isolation
+
+import (
+	"fmt"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+	"github.com/awslabs/kit/operator/pkg/utils/object"
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const defaultPriority = 1000000
+
+// NodeSelectorFor adds the node pool label of an isolated control plane to
+// the selector of a component
+func NodeSelectorFor(controlPlane *v1alpha1.ControlPlane, selector map[string]string) map[string]string {
+	if controlPlane.Spec.Isolation != nil {
+		selector[object.NodePoolLabelKey] = controlPlane.Spec.Isolation.NodePool
+	}
+	return selector
+}
+
+// TolerationsFor returns the toleration of the node pool taint for an
+// isolated control plane
+func TolerationsFor(controlPlane *v1alpha1.ControlPlane) []v1.Toleration {
+	if controlPlane.Spec.Isolation == nil {
+		return nil
+	}
+	return []v1.Toleration{{
+		Key:      object.NodePoolLabelKey,
+		Operator: v1.TolerationOpEqual,
+		Value:    controlPlane.Spec.Isolation.NodePool,
+		Effect:   v1.TaintEffectNoSchedule,
+	}}
+}
+
+// PriorityClassNameFor returns the PriorityClass of an isolated control
+// plane, else the default priority class of the component
+func PriorityClassNameFor(controlPlane *v1alpha1.ControlPlane, defaultPriorityClassName string) string {
+	if controlPlane.Spec.Isolation == nil {
+		return defaultPriorityClassName
+	}
+	return fmt.Sprintf("kit-control-plane-%d", priorityFor(controlPlane.Spec.Isolation))
+}
+
+// PriorityClassFor returns the PriorityClass of an isolated control plane.
+// PriorityClasses are cluster scoped, so they are named after their value and
+// shared by all the clusters using it.
+func PriorityClassFor(controlPlane *v1alpha1.ControlPlane) *schedulingv1.PriorityClass {
+	return &schedulingv1.PriorityClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: PriorityClassNameFor(controlPlane, "")},
+		Value:       priorityFor(controlPlane.Spec.Isolation),
+		Description: "Priority of the KIT control plane pods",
+	}
+}
+
+// PodDisruptionBudgetFor returns a budget allowing one of the pods matching
+// labels to be evicted at a time, which keeps the etcd quorum and two master
+// replicas up while the management nodes are drained.
+func PodDisruptionBudgetFor(controlPlane *v1alpha1.ControlPlane, name string, labels map[string]string) *policyv1beta1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: controlPlane.Namespace,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector:       &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
+}
+
+func priorityFor(isolation *v1alpha1.Isolation) int32 {
+	if isolation.Priority == nil {
+		return defaultPriority
+	}
+	return *isolation.Priority
+}
@@ -23,9 +23,9 @@ import (
 	"github.com/awslabs/kit/operator/pkg/kubeprovider"
 	"github.com/awslabs/kit/operator/pkg/utils/object"
 	"github.com/awslabs/kit/operator/pkg/utils/secrets"
-	"go.uber.org/zap"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
 )
 
 type Provider struct {
@@ -60,6 +60,9 @@ func (c *Provider) ReconcileCertsFor(ctx context.Context, controlPlane *v1alpha1
 			if err != nil {
 				return fmt.Errorf("creating secret objects %v, %w", leafCert.Name, err)
 			}
+			if secretObj, err = c.reissueIfOutdated(ctx, leafCert, secretObj); err != nil {
+				return fmt.Errorf("reissuing %v, %w", leafCert.Name, err)
+			}
 			secretObjs = append(secretObjs, secretObj)
 		}
 		for _, secret := range secretObjs {
@@ -68,7 +71,7 @@ func (c *Provider) ReconcileCertsFor(ctx context.Context, controlPlane *v1alpha1
 			}
 		}
 	}
-	zap.S().Debugf("[%v] Keypairs reconciled", controlPlane.ClusterName())
+	logging.FromContext(ctx).Debug("Keypairs reconciled")
 	return nil
 }
 
@@ -89,6 +92,29 @@ func (c *Provider) GetOrGenerateSecret(ctx context.Context, request *secrets.Req
 	return secret, err
 }
 
+// reissueIfOutdated updates an existing secret with a new cert when its cert
+// is missing names of the request. Secrets holding certs are otherwise never
+// updated.
+func (c *Provider) reissueIfOutdated(ctx context.Context, request *secrets.Request, secret *v1.Secret) (*v1.Secret, error) {
+	if secret.ResourceVersion == "" {
+		return secret, nil
+	}
+	outdated, err := request.Outdated(secret)
+	if err != nil || !outdated {
+		return secret, err
+	}
+	reissued, err := request.Create()
+	if err != nil {
+		return nil, err
+	}
+	secret.Data = reissued.Data
+	if err := c.kubeClient.Update(ctx, secret); err != nil {
+		return nil, fmt.Errorf("updating secret, %w", err)
+	}
+	logging.FromContext(ctx).Infof("Reissued certificate %s missing DNS names", secret.Name)
+	return secret, nil
+}
+
 // GetSecretFromServer will get the secret from API server and validate
 func (c *Provider) GetSecretFromServer(ctx context.Context, nn types.NamespacedName) (*v1.Secret, error) {
 	// get secret from api server
@@ -15,6 +15,7 @@ limitations under the License.
 package object
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -24,14 +25,24 @@ import (
 var (
 	ControlPlaneLabelKey = v1alpha1.SchemeGroupVersion.Group + "/control-plane-name"
 	AppNameLabelKey      = v1alpha1.SchemeGroupVersion.Group + "/app"
+	ClusterSetLabelKey   = v1alpha1.SchemeGroupVersion.Group + "/cluster-set-name"
+	NodePoolLabelKey     = v1alpha1.SchemeGroupVersion.Group + "/node-pool"
+	// ChecksumAnnotationKey is set to the hash of the config of an object, so
+	// the object is replaced or its pods restarted when the config changes
+	ChecksumAnnotationKey = v1alpha1.SchemeGroupVersion.Group + "/checksum"
 )
 
+// WithOwner makes the owner the controller of obj, obj is garbage collected
+// when the owner is deleted and changes to obj trigger a reconcile of the
+// owner. The owner's GVK needs to be set.
 func WithOwner(owner, obj client.Object) client.Object {
 	obj.SetOwnerReferences([]metav1.OwnerReference{{
-		APIVersion: owner.GetObjectKind().GroupVersionKind().Version,
-		Name:       owner.GetName(),
-		Kind:       owner.GetObjectKind().GroupVersionKind().Kind,
-		UID:        owner.GetUID(),
+		APIVersion:         owner.GetObjectKind().GroupVersionKind().GroupVersion().String(),
+		Name:               owner.GetName(),
+		Kind:               owner.GetObjectKind().GroupVersionKind().Kind,
+		UID:                owner.GetUID(),
+		Controller:         aws.Bool(true),
+		BlockOwnerDeletion: aws.Bool(true),
 	}})
 	return obj
 }
@@ -0,0 +1,53 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/awslabs/kit/operator/pkg/apis/controlplane/v1alpha1"
+)
+
+// MaxParallel bounds how many reconcilers run at the same time for a cluster
+const MaxParallel = 4
+
+// Func reconciles one part of a control plane
+type Func func(context.Context, *v1alpha1.ControlPlane) error
+
+// Parallel runs independent reconcilers concurrently, at most MaxParallel at
+// a time. All of them run to completion, the error of the first one in the
+// given order to fail is returned so that results are deterministic.
+func Parallel(ctx context.Context, controlPlane *v1alpha1.ControlPlane, reconcilers ...Func) error {
+	errs := make([]error, len(reconcilers))
+	limit := make(chan struct{}, MaxParallel)
+	wg := sync.WaitGroup{}
+	for i, reconcile := range reconcilers {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, reconcile Func) {
+			defer func() { <-limit }()
+			defer wg.Done()
+			errs[i] = reconcile(ctx, controlPlane)
+		}(i, reconcile)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
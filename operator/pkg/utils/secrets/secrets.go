@@ -63,6 +63,21 @@ func (r *Request) Create() (secret *v1.Secret, err error) {
 	return secretObjWithKeyPair(object.NamespacedName(r.Name, r.Namespace), private, public), nil
 }
 
+// Outdated returns true if the cert in the secret was signed without some of
+// the DNS names of the request, e.g. names added to the spec after the cert
+// was first created.
+func (r *Request) Outdated(secret *v1.Secret) (bool, error) {
+	if r.Type != KeyWithSignedCert || len(r.AltNames.DNSNames) == 0 {
+		return false, nil
+	}
+	_, cert := Parse(secret)
+	hasNames, err := pkiutil.HasDNSNames(cert, r.AltNames.DNSNames)
+	if err != nil {
+		return false, err
+	}
+	return !hasNames, nil
+}
+
 func IsValid(secret *v1.Secret) error {
 	// TODO
 	switch secret.Type {